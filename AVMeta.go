@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/ylqjgm/AVMeta/pkg/cmd"
 )
 
@@ -14,6 +16,7 @@ func main() {
 	e := cmd.NewExecutor(version, commit, built)
 
 	if err := e.Execute(); err != nil {
-		panic(err)
+		// 错误信息已由命令输出
+		os.Exit(1)
 	}
 }
@@ -1,12 +1,10 @@
 package actress
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 
 	"github.com/ylqjgm/AVMeta/pkg/util"
@@ -70,8 +68,8 @@ func (emby *Emby) Actor(name, face string) error {
 
 // 本地上传演员头像
 func (emby *Emby) uploadImage(id, face string) error {
-	// 图片编码
-	body, err := emby.base64Encode(face)
+	// 图片编码，检查图片能否解码，头像为本地图片，不使用代理
+	body, err := util.ImageBase64(face, "", util.ImageStruct{})
 	// 检查
 	if err != nil {
 		return err
@@ -120,29 +118,3 @@ func (emby *Emby) makeRequest(method, uri, body string) ([]byte, error) {
 
 	return data, err
 }
-
-// 文件Base64编码
-func (emby *Emby) base64Encode(file string) (string, error) {
-	// 检查错误
-	f, err := os.Open(file)
-	// 如果出错
-	if err != nil {
-		return "", err
-	}
-	// 关闭
-	defer f.Close()
-
-	// 初始化byte
-	buff := make([]byte, 500000)
-	// 读取文件
-	n, err := f.Read(buff)
-	// 检查错误
-	if err != nil {
-		return "", err
-	}
-
-	// Base64编码
-	source := base64.StdEncoding.EncodeToString(buff[:n])
-
-	return source, nil
-}
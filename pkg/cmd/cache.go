@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+// cache命令
+func (e *Executor) initCache() {
+	cacheCmd := &cobra.Command{
+		Use: "cache",
+		Long: `
+管理刮削时保存的网页及图片缓存`,
+		Example: `  AVMeta cache stats
+  AVMeta cache clear`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"clear", "stats"},
+		RunE:      e.cacheRunFunc,
+	}
+
+	e.rootCmd.AddCommand(cacheCmd)
+}
+
+// 缓存执行命令
+func (e *Executor) cacheRunFunc(cmd *cobra.Command, args []string) error {
+	// 清空缓存
+	if args[0] == "clear" {
+		// 清空
+		if err := util.ClearCache(); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "缓存已清空: %s\n", util.CacheDir())
+
+		return nil
+	}
+
+	// 统计缓存
+	info, err := util.CacheStats()
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "缓存目录:\t%s\n", info.Path)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "文件数量:\t%d\n", info.Files)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "已过期:\t\t%d\n", info.Expired)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "缓存大小:\t%s\n", formatSize(info.Size))
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "缓存状态:\t%s\n", map[bool]string{true: "启用", false: "禁用"}[e.cfg.Cache.Enable])
+
+	return nil
+}
+
+// 格式化文件大小
+func formatSize(size int64) string {
+	// 单位
+	units := []string{"B", "KB", "MB", "GB"}
+	// 转换
+	val := float64(size)
+	i := 0
+	for val >= 1024 && i < len(units)-1 {
+		val /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.2f %s", val, units[i])
+}
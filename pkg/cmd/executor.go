@@ -6,11 +6,15 @@ AVMeta程序所有操作命令皆由此包定义，使用 cobra 第三方包编
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/ylqjgm/AVMeta/pkg/logs"
 	"github.com/ylqjgm/AVMeta/pkg/util"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 )
 
 // 采集站点变量
@@ -20,6 +24,7 @@ var site string
 type Executor struct {
 	rootCmd *cobra.Command
 	cfg     *util.ConfigStruct
+	index   *util.Index // 整理索引
 
 	version   string
 	commit    string
@@ -47,14 +52,44 @@ func NewExecutor(version, commit, built string) *Executor {
 	e.initConfigFile()
 	e.initActress()
 	e.initNfo()
+	e.initRefresh()
+	e.initScrape()
+	e.initTemplate()
+	e.initUndo()
+	e.initVSMeta()
+	e.initWatch()
+	e.initCache()
 	e.initVersion()
 
 	return e
 }
 
 // Execute 执行根命令。
+// 执行期间接收到中断信号时，将取消命令上下文以中断正在进行的刮削，
+// 再次接收到中断信号则直接退出。
 func (e *Executor) Execute() error {
-	return e.rootCmd.Execute()
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 监听中断信号
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	go func() {
+		select {
+		case <-sig:
+			fmt.Fprintln(os.Stderr, "接收到中断信号, 正在停止, 再次中断将直接退出...")
+			// 取消上下文
+			cancel()
+			// 恢复默认信号处理
+			signal.Stop(sig)
+		case <-ctx.Done():
+		}
+	}()
+
+	return e.rootCmd.ExecuteContext(ctx)
 }
 
 // 初始化配置
@@ -68,4 +103,9 @@ func (e *Executor) initConfig() {
 
 	// 配置信息
 	e.cfg = cfg
+
+	// 应用网络配置
+	util.SetNetwork(cfg.Network)
+	// 应用缓存配置
+	util.SetCache(cfg.Cache)
 }
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ylqjgm/AVMeta/pkg/logs"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// 是否忽略整理索引
+var forceRun bool
+
+// 读取整理索引，读取失败时不使用索引
+func (e *Executor) openIndex() {
+	// 读取索引
+	idx, err := util.OpenIndex(util.IndexPath())
+	// 检查
+	if err != nil {
+		logs.Warning("整理索引读取失败, 将整理所有文件, 错误原因: %s", err)
+		return
+	}
+
+	e.index = idx
+}
+
+// 保存整理索引
+func (e *Executor) saveIndex() {
+	// 未使用索引
+	if e.index == nil {
+		return
+	}
+
+	// 保存
+	if err := e.index.Save(); err != nil {
+		logs.Warning("整理索引保存失败, 错误原因: %s", err)
+	}
+}
+
+// 根据整理索引过滤文件，跳过所有分段均已整理成功且未变化的视频，
+// 返回需要整理的文件及各状态的文件数量。
+func (e *Executor) filterIndexed(files []string) ([]string, map[string]int) {
+	// 各状态数量
+	stats := make(map[string]int)
+
+	// 未使用索引
+	if e.index == nil {
+		stats[util.IndexNew] = len(files)
+		return files, stats
+	}
+
+	// 需要整理的文件
+	var todo []string
+	for _, v := range util.GroupVideos(files) {
+		// 是否所有分段均已整理
+		skip := !forceRun
+		for _, file := range v.Files() {
+			status, _ := e.index.Status(file)
+			stats[status]++
+			if status != util.IndexUnchanged {
+				skip = false
+			}
+		}
+
+		// 跳过已整理的视频
+		if skip {
+			continue
+		}
+		todo = append(todo, v.Files()...)
+	}
+
+	return todo, stats
+}
+
+// 输出文件变化情况
+func logIndexStats(stats map[string]int) {
+	// 仅输出计划时输出到标准错误，避免影响计划内容
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "新文件 %d 个, 已变化 %d 个, 上次失败 %d 个, 已整理 %d 个\n",
+			stats[util.IndexNew], stats[util.IndexChanged], stats[util.IndexFailed], stats[util.IndexUnchanged])
+		return
+	}
+
+	logs.Info("新文件 %d 个, 已变化 %d 个, 上次失败 %d 个, 已整理 %d 个",
+		stats[util.IndexNew], stats[util.IndexChanged], stats[util.IndexFailed], stats[util.IndexUnchanged])
+
+	// 是否跳过
+	if stats[util.IndexUnchanged] > 0 && !forceRun {
+		logs.Info("跳过 %d 个已整理且未变化的文件, 可使用 --force 参数重新整理", stats[util.IndexUnchanged])
+	}
+}
+
+// 获取视频各分段文件的索引条目，需在整理前调用
+func (e *Executor) indexEntries(v util.Video) []util.IndexEntry {
+	// 未使用索引
+	if e.index == nil {
+		return nil
+	}
+
+	entries := make([]util.IndexEntry, 0, len(v.Parts))
+	for _, part := range v.Parts {
+		entries = append(entries, util.NewIndexEntry(part.File))
+	}
+
+	return entries
+}
+
+// 记录视频的整理结果
+func (e *Executor) recordIndex(v util.Video, entries []util.IndexEntry, m *media.Media, err error) {
+	// 未使用索引
+	if e.index == nil {
+		return
+	}
+
+	// 番号
+	code := util.ParseCode(v.Name, e.cfg.Code, e.cfg.Path.Filter).Code
+
+	for i, entry := range entries {
+		entry.Code = code
+		if err != nil {
+			entry.Result = util.IndexFail
+			entry.Error = err.Error()
+			// 移动方式下失败的文件将被移动到失败目录
+			if e.cfg.Path.Mode == "" || e.cfg.Path.Mode == util.ModeMove {
+				entry.Target = util.FailPath(entry.File, e.cfg)
+			}
+		} else {
+			entry.Result = util.IndexSuccess
+			entry.Code = m.Number
+			entry.Source = m.Source
+			entry.Dest = m.DirPath
+			entry.Target = media.VideoPath(m, e.cfg, v.Parts[i])
+		}
+		e.index.Put(entry)
+	}
+}
+
+// 加入失败目录中的视频文件，用于重新整理上次失败的影片，已在列表中的文件不重复加入
+func (e *Executor) withFailFiles(files []string) []string {
+	// 失败目录
+	dir := filepath.Join(util.OutputPath(e.cfg), e.cfg.Path.Fail)
+	if !util.Exists(dir) {
+		return files
+	}
+
+	// 列目录
+	fails, err := util.WalkDir(dir, e.cfg)
+	// 检查
+	if err != nil {
+		logs.Warning("失败目录读取失败, 错误原因: %s", err)
+	}
+
+	// 去重
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		seen[filepath.Clean(file)] = true
+	}
+	for _, file := range fails {
+		if !seen[filepath.Clean(file)] {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
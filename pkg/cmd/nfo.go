@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"encoding/xml"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/ylqjgm/AVMeta/pkg/logs"
@@ -14,20 +13,6 @@ import (
 	"strings"
 )
 
-var (
-	// 定义扩展列表
-	videoExts = map[string]string{
-		".avi":  ".avi",
-		".flv":  ".flv",
-		".mkv":  ".mkv",
-		".mov":  ".mov",
-		".mp4":  ".mp4",
-		".rmvb": ".rmvb",
-		".ts":   ".ts",
-		".wmv":  ".wmv",
-	}
-)
-
 // NfoFile nfo文件列表结构
 type NfoFile struct {
 	Path  string
@@ -40,7 +25,7 @@ func (e *Executor) initNfo() {
 	nfoCmd := &cobra.Command{
 		Use: "nfo",
 		Long: `
-自动将运行目录下所有nfo文件转换为VSMeta文件`,
+自动将运行目录 (或 --input 指定目录) 下所有nfo文件转换为VSMeta文件`,
 		Example: `  AVMeta nfo`,
 		Run:     e.nfoRunFunc,
 	}
@@ -53,8 +38,8 @@ func (e *Executor) nfoRunFunc(cmd *cobra.Command, args []string) {
 	// 初始化日志
 	logs.Log("")
 
-	// 获取当前执行路径
-	curDir := util.GetRunPath()
+	// 获取要转换的目录
+	curDir := util.InputPath(e.cfg)
 
 	// 文件列表
 	var nfos []NfoFile
@@ -86,8 +71,8 @@ func (e *Executor) nfoRunFunc(cmd *cobra.Command, args []string) {
 
 // 转换进程
 func (e *Executor) nfoProcess(nfo NfoFile, wg *util.WaitGroup) {
-	// 读取文件
-	b, err := util.ReadFile(nfo.Path)
+	// 读取文件，兼容 emby、kodi、jellyfin 格式
+	m, err := media.ReadNfo(nfo.Path)
 	// 检查
 	if err != nil {
 		// 输出错误
@@ -99,24 +84,10 @@ func (e *Executor) nfoProcess(nfo NfoFile, wg *util.WaitGroup) {
 		return
 	}
 
-	// 媒体对象
-	var m media.Media
-
-	// 转换
-	err = xml.Unmarshal(b, &m)
-	// 检查错误
-	if err != nil {
-		// 输出错误
-		logs.Error("文件: [%s] 打开失败, 错误原因: %s\n", path.Base(nfo.Path), err)
-
-		// 进程
-		wg.Done()
-
-		return
-	}
-
 	// 实例化vsmeta
 	vs := media.NewVSMeta()
+	vs.Image = e.cfg.Image
+	vs.Proxy = e.cfg.Base.Proxy
 	// fanart
 	if !util.Exists(nfo.Dir+"/fanart.jpg") && m.FanArt != "" {
 		err = util.SavePhoto(m.FanArt, fmt.Sprintf("%s/fanart.jpg", nfo.Dir), "", !strings.EqualFold(strings.ToLower(path.Ext(m.FanArt)), ".jpg"))
@@ -138,7 +109,7 @@ func (e *Executor) nfoProcess(nfo NfoFile, wg *util.WaitGroup) {
 	m.Poster = fmt.Sprintf("%s/poster.jpg", nfo.Dir)
 
 	// 解析为 vsmeta
-	bs := vs.Convert(&m)
+	bs := vs.Convert(m)
 
 	// 获取视频后缀
 	ext := path.Ext(nfo.Video)
@@ -178,11 +149,8 @@ func (e *Executor) walk(dirPath string, nfoFiles []NfoFile) ([]NfoFile, error) {
 			fullDir := dirPath + "/" + f.Name()
 			nfoFiles, err = e.walk(fullDir, nfoFiles)
 		} else {
-			// 获取后缀并转换为小写
-			ext := strings.ToLower(path.Ext(f.Name()))
-
 			// 验证是否存在于后缀扩展名中
-			if _, ok := videoExts[ext]; ok {
+			if util.IsVideoExt(f.Name(), e.cfg.Path.Exts) {
 				// 遍历目录
 				err := filepath.Walk(dirPath, func(filePath string, fi os.FileInfo, err error) error {
 					// 错误
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+var (
+	// 是否仅输出整理计划
+	dryRun bool
+	// 整理计划输出格式
+	planFormat string
+	// 整理计划输出文件
+	planFile string
+)
+
+// 整理计划输出结构
+type planOutput struct {
+	File     string          `json:"file"`
+	Code     string          `json:"code"`
+	Dir      string          `json:"dir,omitempty"`
+	Target   string          `json:"target,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Attempts []scrapeAttempt `json:"attempts"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// 生成整理计划，刮削所有文件但不做任何改动
+func (e *Executor) dryRunProcess(ctx context.Context, files []string) error {
+	// 检查输出格式
+	planFormat = strings.ToLower(planFormat)
+	if planFormat != "table" && planFormat != "csv" && planFormat != "json" {
+		return fmt.Errorf("不支持的输出格式: %s", planFormat)
+	}
+
+	// 合并分段视频
+	videos := util.GroupVideos(files)
+	// 计划列表，按视频顺序保存
+	results := make([][]planOutput, len(videos))
+
+	// 初始化进程
+	wg := util.NewWaitGroup(2)
+
+	// 循环视频列表
+	for i, v := range videos {
+		// 是否已中断
+		if ctx.Err() != nil {
+			break
+		}
+		// 计数加
+		wg.AddDelta()
+		// 计划进程
+		go func(i int, v util.Video) {
+			defer wg.Done()
+
+			for _, item := range media.Plan(ctx, v, e.cfg) {
+				results[i] = append(results[i], toPlanOutput(item))
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(videos), v.Name)
+		}(i, v)
+	}
+
+	// 等待结束
+	wg.Wait()
+
+	// 合并计划
+	var plans []planOutput
+	for _, r := range results {
+		plans = append(plans, r...)
+	}
+
+	// 是否被中断
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 输出对象
+	var w io.Writer = os.Stdout
+	// 是否输出到文件
+	if planFile != "" {
+		f, err := os.Create(planFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	return writePlan(w, plans)
+}
+
+// 转换为输出结构
+func toPlanOutput(item *media.PlanItem) planOutput {
+	out := planOutput{
+		File:     item.File,
+		Code:     item.Code,
+		Dir:      item.Dir,
+		Target:   item.Target,
+		Source:   item.Source,
+		Attempts: []scrapeAttempt{},
+	}
+	for _, a := range item.Attempts {
+		sa := scrapeAttempt{Site: a.Site}
+		if a.Err != nil {
+			sa.Error = a.Err.Error()
+		}
+		out.Attempts = append(out.Attempts, sa)
+	}
+	if item.Err != nil {
+		out.Error = item.Err.Error()
+	}
+
+	return out
+}
+
+// 按格式输出整理计划
+func writePlan(w io.Writer, plans []planOutput) error {
+	switch planFormat {
+	case "json":
+		// 转换为json
+		b, err := json.MarshalIndent(plans, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+
+		return err
+	case "csv":
+		cw := csv.NewWriter(w)
+		// 表头
+		_ = cw.Write([]string{"file", "code", "dir", "target", "source", "failures", "error"})
+		for _, p := range plans {
+			_ = cw.Write([]string{p.File, p.Code, p.Dir, p.Target, p.Source, planFailures(p), p.Error})
+		}
+		cw.Flush()
+
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "文件\t番号\t来源\t目标路径\t失败原因")
+	for _, p := range plans {
+		// 目标路径
+		target := p.Target
+		if p.Error != "" {
+			target = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.File, p.Code, p.Source, target, planFailures(p))
+	}
+
+	return tw.Flush()
+}
+
+// 组合失败原因
+func planFailures(p planOutput) string {
+	// 失败列表
+	var failures []string
+	for _, a := range p.Attempts {
+		if a.Error != "" {
+			failures = append(failures, fmt.Sprintf("[%s] %s", a.Site, a.Error))
+		}
+	}
+	// 刮削尝试以外的错误，如没有可用刮削器或找不到封面
+	if p.Error != "" && (len(p.Attempts) == 0 || p.Attempts[len(p.Attempts)-1].Error == "") {
+		failures = append(failures, p.Error)
+	}
+
+	return strings.Join(failures, "; ")
+}
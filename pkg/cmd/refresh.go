@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/logs"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+var (
+	// 是否重新下载图片
+	refreshArtwork bool
+	// 仅比较数据
+	refreshDryRun bool
+)
+
+// refresh命令
+func (e *Executor) initRefresh() {
+	refreshCmd := &cobra.Command{
+		Use: "refresh [dir]",
+		Long: `
+重新刮削已整理影片的元数据, 在原位置更新 nfo、vsmeta 及图片, 不会移动视频文件,
+未指定目录时刷新输出目录下的成功目录
+
+nfo 中 lockdata 为 true 的影片将被跳过, lockedfields 中列出的字段 (以 | 分隔) 保持不变,
+可用字段: title plot studio director release runtime actor tag genre writer set cover website rating,
+同时支持 Emby/Jellyfin 的字段名称, 如 Name、Overview、Cast、Genres
+
+整理及刷新时会在影片目录中写入刮削记录 .avmeta.json, 与记录不一致的字段视为手动修改过, 同样保持不变,
+没有刮削记录的影片 (旧版本整理) 无法识别手动修改, 首次刷新前请先使用 --dry-run 检查,
+没有 nfo 的目录将读取 vsmeta 文件刷新, vsmeta 不支持锁定字段, 只保留手动修改过的字段`,
+		Example: `  AVMeta refresh
+  AVMeta refresh /data/av/success --dry-run
+  AVMeta refresh /data/av/success/SSIS-001 --site javbus --artwork`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: e.refreshRunFunc,
+	}
+
+	// 添加参数
+	refreshCmd.Flags().StringVar(&site, "site", "", "指定刮削来源, 为空则按番号自动选择")
+	refreshCmd.Flags().BoolVar(&refreshArtwork, "artwork", false, "重新下载封面及背景图片")
+	refreshCmd.Flags().BoolVar(&refreshDryRun, "dry-run", false, "仅输出将要更新的字段, 不写入任何文件")
+
+	e.rootCmd.AddCommand(refreshCmd)
+}
+
+// 刷新执行命令
+func (e *Executor) refreshRunFunc(cmd *cobra.Command, args []string) error {
+	// 获取刷新目录
+	dir := filepath.Join(util.OutputPath(e.cfg), e.cfg.Path.Success)
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	// 检查目录
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: 不是目录", dir)
+	}
+	// 检查 nfo 格式
+	err = media.CheckProfile(e.cfg.Media.Profile)
+	if err != nil {
+		return err
+	}
+
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 初始化日志
+	logs.Log("")
+
+	// 获取上下文
+	ctx := cmd.Context()
+
+	// 列目录
+	nfos, err := util.WalkNfo(dir, nil)
+	// 检查
+	if err != nil {
+		return err
+	}
+	// 没有 nfo 的 vsmeta 目录
+	nfos, err = util.WalkVSMeta(dir, nfos)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 输出总量
+	logs.Info("共探索到 %d 个元数据文件, 开始刷新...\n\n", len(nfos))
+
+	// 初始化进程
+	wg := util.NewWaitGroup(2)
+	// 刷新结果
+	var updated, failed int32
+
+	// 循环nfo文件列表
+	for _, nfo := range nfos {
+		// 计数加
+		wg.AddDelta()
+		// 是否已中断
+		if ctx.Err() != nil {
+			wg.Done()
+			break
+		}
+		// 刷新进程
+		go func(nfo util.NfoFile) {
+			defer wg.Done()
+
+			switch ok, err := e.refreshProcess(ctx, nfo); {
+			case err != nil && ctx.Err() == nil:
+				atomic.AddInt32(&failed, 1)
+			case ok:
+				atomic.AddInt32(&updated, 1)
+			}
+		}(nfo)
+	}
+
+	// 等待结束
+	wg.Wait()
+
+	// 是否被中断
+	if ctx.Err() != nil {
+		logs.Warning("刷新已中断")
+	}
+
+	logs.Info("共更新 %d 部影片, 失败 %d 部", atomic.LoadInt32(&updated), atomic.LoadInt32(&failed))
+
+	return nil
+}
+
+// 刷新进程，返回是否有更新及错误信息
+func (e *Executor) refreshProcess(ctx context.Context, nfo util.NfoFile) (bool, error) {
+	// 文件名称
+	name := path.Base(nfo.Path)
+
+	// 刷新
+	r, err := media.Refresh(ctx, nfo, e.cfg, media.RefreshOptions{
+		Site:    site,
+		Artwork: refreshArtwork,
+		DryRun:  refreshDryRun,
+	})
+	// 已锁定
+	if err == media.ErrLocked {
+		logs.Info("文件 [%s] 元数据已锁定, 跳过", name)
+		return false, nil
+	}
+	// 检查
+	if err != nil {
+		// 输出失败来源
+		if r != nil {
+			for i, a := range r.Attempts {
+				if a.Err != nil {
+					logs.Info("文件 [%s] 第 %d 次刮削失败, 刮削来源: [%s], 错误原因: %s", name, i+1, a.Site, a.Err)
+				}
+			}
+		}
+		logs.Error("文件 [%s] 刷新失败, 错误原因: %s", name, err)
+
+		return false, err
+	}
+
+	// 输出结果
+	msg := r.String()
+	if refreshDryRun && len(r.Changed) > 0 {
+		msg = "将更新 " + strings.Join(r.Changed, ", ")
+		if len(r.Kept) > 0 {
+			msg += ", 保留手动修改 " + strings.Join(r.Kept, ", ")
+		}
+	}
+	logs.Info("文件 [%s] %s, 来源 [%s], 路径 [%s]", name, msg, r.Media.Source, nfo.Dir)
+
+	return len(r.Changed) > 0, nil
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"github.com/ylqjgm/AVMeta/pkg/logs"
 	"path"
+	"sync/atomic"
 
 	"github.com/ylqjgm/AVMeta/pkg/media"
 
@@ -11,6 +13,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	// 要整理的目录
+	inputDir string
+	// 输出目录
+	outputDir string
+)
+
 func (e *Executor) initRoot() {
 	e.rootCmd = &cobra.Command{
 		Use:   "AVMeta",
@@ -19,8 +28,21 @@ func (e *Executor) initRoot() {
 AVMeta 是一款使用 Golang 编写的跨平台 AV 元数据刮削器
 使用 AVMeta, 您可自动将 AV 电影进行归类整理
 并生成对应媒体库元数据文件`,
-		Run: e.rootRunFunc,
+		Example: `  AVMeta
+  AVMeta --input /data/downloads --output /data/av
+  AVMeta --dry-run
+  AVMeta --dry-run --plan-format csv --plan-file plan.csv`,
+		PersistentPreRun: e.rootPreRunFunc,
+		Run:              e.rootRunFunc,
 	}
+
+	// 添加参数
+	e.rootCmd.PersistentFlags().StringVar(&inputDir, "input", "", "要整理的目录, 默认使用配置文件中的 path.input")
+	e.rootCmd.PersistentFlags().StringVar(&outputDir, "output", "", "整理后的输出目录, 默认使用配置文件中的 path.output")
+	e.rootCmd.Flags().BoolVar(&forceRun, "force", false, "忽略整理索引, 重新整理已整理过且未变化的文件")
+	e.rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "仅刮削并输出整理计划, 不创建、移动任何文件")
+	e.rootCmd.Flags().StringVar(&planFormat, "plan-format", "table", "整理计划输出格式: table, csv, json")
+	e.rootCmd.Flags().StringVar(&planFile, "plan-file", "", "整理计划输出文件, 默认输出到标准输出")
 }
 
 func (e *Executor) setTemplate() {
@@ -34,7 +56,14 @@ func (e *Executor) setTemplate() {
 
 命令:
   actress     头像下载、入库
+  cache       网页及图片缓存管理
   nfo         nfo文件转换为VSMeta文件
+  refresh     刷新已整理影片的元数据
+  scrape      刮削番号并输出元数据
+  template    路径模板测试
+  undo        撤销整理操作
+  vsmeta      vsmeta文件解析及转换为nfo文件
+  watch       监控目录自动整理
   help        命令执行帮助
   init        生成配置文件
   version     显示程序版本{{end}}{{if .HasAvailableSubCommands}}
@@ -44,57 +73,183 @@ func (e *Executor) setTemplate() {
 `)
 }
 
+// 应用命令行中的路径参数
+func (e *Executor) rootPreRunFunc(cmd *cobra.Command, _ []string) {
+	// 要整理的目录
+	if inputDir != "" {
+		e.cfg.Path.Input = inputDir
+	}
+	// 输出目录
+	if outputDir != "" {
+		e.cfg.Path.Output = outputDir
+	}
+}
+
 // root命令执行函数
-func (e *Executor) rootRunFunc(_ *cobra.Command, _ []string) {
-	// 初始化日志
-	logs.Log("logs")
+func (e *Executor) rootRunFunc(cmd *cobra.Command, _ []string) {
+	// 初始化日志，仅输出计划时不写入日志文件
+	if dryRun {
+		logs.Log("")
+	} else {
+		logs.Log("logs")
+	}
+
+	// 获取上下文
+	ctx := cmd.Context()
 
-	// 获取当前执行路径
-	curDir := util.GetRunPath()
+	// 检查保存路径及文件名称模板
+	logs.FatalError(util.CheckTemplate(e.cfg, (&media.Media{}).ConvertMap()))
 
-	// 列当前目录
-	files, err := util.WalkDir(curDir, e.cfg.Path.Success, e.cfg.Path.Fail)
+	// 获取要整理的目录
+	curDir := util.InputPath(e.cfg)
+
+	// 列目录
+	files, err := util.WalkDir(curDir, e.cfg)
 	// 错误日志
 	logs.FatalError(err)
+	// 加入失败目录中的文件，重新整理上次失败的影片
+	files = e.withFailFiles(files)
+
+	// 读取整理索引，跳过已整理的文件
+	e.openIndex()
+	files, stats := e.filterIndexed(files)
+	logIndexStats(stats)
+
+	// 仅输出整理计划
+	if dryRun {
+		logs.FatalError(e.dryRunProcess(ctx, files))
+		return
+	}
+
+	// 检查整理方式及 nfo 格式
+	logs.FatalError(util.CheckMode(e.cfg.Path.Mode))
+	logs.FatalError(media.CheckProfile(e.cfg.Media.Profile))
+
+	// 输出大文件复制进度
+	util.SetProgress(copyProgress)
+	defer util.SetProgress(nil)
+
+	// 创建整理日志，用于撤销本次整理
+	journal, err := util.NewJournal()
+	// 检查
+	if err != nil {
+		logs.Warning("整理日志创建失败, 本次整理将无法撤销, 错误原因: %s", err)
+	} else {
+		util.SetJournal(journal)
+		defer func() {
+			util.SetJournal(nil)
+			_ = journal.Close()
+		}()
+		logs.Info("本次整理编号 [%s], 可使用 AVMeta undo %s 撤销本次整理", journal.ID(), journal.ID())
+	}
+
+	// 保存整理索引
+	defer e.saveIndex()
+
+	// 合并分段视频
+	videos := util.GroupVideos(files)
 
 	// 获取总量
 	count := len(files)
 	// 输出总量
-	logs.Info("\n\n共探索到 %d 个视频文件, 开始刮削整理...\n\n", count)
+	logs.Info("\n\n共探索到 %d 个视频文件, 共 %d 部影片, 开始刮削整理...\n\n", count, len(videos))
 
 	// 初始化进程
 	wg := util.NewWaitGroup(2)
+	// 整理结果
+	var success, fail int32
 
-	// 循环视频文件列表
-	for _, file := range files {
+	// 循环视频列表
+	for _, v := range videos {
 		// 计数加
 		wg.AddDelta()
+		// 是否已中断
+		if ctx.Err() != nil {
+			wg.Done()
+			break
+		}
 		// 刮削进程
-		go e.packProcess(file, wg)
+		go e.packProcess(ctx, v, wg, &success, &fail)
 	}
 
 	// 等待结束
 	wg.Wait()
+
+	// 是否被中断
+	if ctx.Err() != nil {
+		logs.Warning("刮削整理已中断")
+	}
+
+	// 输出整理结果
+	logs.Info("本次整理成功 %d 部, 失败 %d 部", atomic.LoadInt32(&success), atomic.LoadInt32(&fail))
 }
 
 // 刮削进程
-func (e *Executor) packProcess(file string, wg *util.WaitGroup) {
+func (e *Executor) packProcess(ctx context.Context, v util.Video, wg *util.WaitGroup, success, fail *int32) {
 	// 刮削整理
-	m, err := media.Pack(file, e.cfg)
+	err := e.packVideo(ctx, v)
+	// 统计结果，被中断的不计入
+	if err == nil {
+		atomic.AddInt32(success, 1)
+	} else if ctx.Err() == nil {
+		atomic.AddInt32(fail, 1)
+	}
+
+	// 进程
+	wg.Done()
+}
+
+// 刮削整理单个视频，失败时将文件移动到失败目录，并返回错误信息
+func (e *Executor) packVideo(ctx context.Context, v util.Video) error {
+	// 文件名称
+	file := v.Name
+	// 整理前的文件信息
+	entries := e.indexEntries(v)
+	// 刮削整理
+	m, err := media.PackVideo(ctx, v, e.cfg)
 	// 检查
 	if err != nil {
-		// 恢复文件
-		util.FailFile(file, e.cfg.Path.Fail)
+		// 被中断的文件保留在原处
+		if ctx.Err() != nil {
+			logs.Info("文件 [%s] 刮削已中断", path.Base(file))
 
-		// 进程
-		wg.Done()
+			return err
+		}
 
-		return
+		// 记录失败
+		e.recordIndex(v, entries, nil, err)
+
+		// 恢复文件，非移动方式保留原文件
+		if e.cfg.Path.Mode == "" || e.cfg.Path.Mode == util.ModeMove {
+			for _, f := range v.Files() {
+				util.FailFile(f, e.cfg)
+			}
+		}
+
+		return err
 	}
 
+	// 记录成功
+	e.recordIndex(v, entries, m, nil)
+
 	// 输出正确
 	logs.Info("文件 [%s] 刮削成功, 来源 [%s], 路径 [%s]", path.Base(file), m.Source, m.DirPath)
 
-	// 进程
-	wg.Done()
+	return nil
+}
+
+// 复制进度输出
+func copyProgress(file string, done, total int64) {
+	// 仅输出大文件进度
+	if total < 64*1024*1024 {
+		return
+	}
+
+	// 百分比
+	percent := 100.0
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+
+	logs.Info("文件 [%s] 复制中 %.1f%% (%s/%s)", path.Base(file), percent, formatSize(done), formatSize(total))
 }
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+)
+
+// 输出格式变量
+var scrapeFormat string
+
+// 刮削结果输出结构
+type scrapeOutput struct {
+	Code     string            `json:"code"`
+	Source   string            `json:"source,omitempty"`
+	Attempts []scrapeAttempt   `json:"attempts"`
+	Media    *scrapeMedia      `json:"media,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Sources  map[string]string `json:"sources,omitempty"`
+}
+
+// 刮削尝试输出结构
+type scrapeAttempt struct {
+	Site  string `json:"site"`
+	Error string `json:"error,omitempty"`
+}
+
+// 刮削数据输出结构
+type scrapeMedia struct {
+	Number   string        `json:"number"`
+	Title    string        `json:"title"`
+	Studio   string        `json:"studio"`
+	Director string        `json:"director"`
+	Release  string        `json:"release"`
+	Runtime  string        `json:"runtime"`
+	Series   string        `json:"series"`
+	Tags     []string      `json:"tags"`
+	Actors   []media.Actor `json:"actors"`
+	Cover    string        `json:"cover"`
+	WebSite  string        `json:"website"`
+	Plot     string        `json:"plot"`
+}
+
+// scrape命令
+func (e *Executor) initScrape() {
+	scrapeCmd := &cobra.Command{
+		Use: "scrape <code>",
+		Long: `
+刮削给定番号并输出元数据, 不会下载图片或移动任何文件,
+可用于排查影片刮削失败的原因`,
+		Example: `  AVMeta scrape ABP-123
+  AVMeta scrape ABP-123 --site javbus
+  AVMeta scrape ABP-123 --format json
+  AVMeta scrape ABP-123 --format nfo > ABP-123.nfo`,
+		Args: cobra.ExactArgs(1),
+		RunE: e.scrapeRunFunc,
+	}
+
+	// 添加参数
+	scrapeCmd.Flags().StringVar(&site, "site", "", "强制使用的刮削器, 如: javbus, javdb, dmm")
+	scrapeCmd.Flags().StringVar(&scrapeFormat, "format", "table", "输出格式: table, json, nfo")
+	e.rootCmd.AddCommand(scrapeCmd)
+}
+
+// 刮削执行命令
+func (e *Executor) scrapeRunFunc(cmd *cobra.Command, args []string) error {
+	// 检查输出格式
+	scrapeFormat = strings.ToLower(scrapeFormat)
+	if scrapeFormat != "table" && scrapeFormat != "json" && scrapeFormat != "nfo" {
+		return fmt.Errorf("不支持的输出格式: %s", scrapeFormat)
+	}
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 刮削
+	m, attempts, err := media.Scrape(cmd.Context(), args[0], e.cfg, site)
+
+	// 组合输出结构
+	out := scrapeOutput{Code: strings.ToUpper(args[0]), Attempts: []scrapeAttempt{}}
+	for _, a := range attempts {
+		sa := scrapeAttempt{Site: a.Site}
+		if a.Err != nil {
+			sa.Error = a.Err.Error()
+		}
+		out.Attempts = append(out.Attempts, sa)
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	if m != nil {
+		out.Source = m.Source
+		out.Sources = m.Sources
+		out.Media = toScrapeMedia(m)
+	}
+
+	// 输出
+	switch scrapeFormat {
+	case "json":
+		// 转换为json
+		b, jerr := json.MarshalIndent(out, "", "  ")
+		if jerr != nil {
+			return jerr
+		}
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	case "nfo":
+		// 尝试记录输出到错误输出，保证标准输出为完整的nfo
+		writeAttempts(cmd.ErrOrStderr(), out.Attempts)
+		if m != nil {
+			// 转换为nfo
+			b, xerr := m.NFO(e.cfg.Media.Profile)
+			if xerr != nil {
+				return xerr
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+		}
+	default:
+		writeScrapeTable(cmd.OutOrStdout(), &out)
+	}
+
+	// 刮削失败
+	if err != nil {
+		// 没有任何刮削尝试时直接返回错误原因
+		if len(attempts) == 0 {
+			return err
+		}
+
+		return fmt.Errorf("%s: 刮削失败", out.Code)
+	}
+
+	return nil
+}
+
+// 转换为输出结构
+func toScrapeMedia(m *media.Media) *scrapeMedia {
+	// 标签
+	tags := make([]string, 0, len(m.Tag))
+	for _, tag := range m.Tag {
+		tags = append(tags, tag.Inner)
+	}
+
+	return &scrapeMedia{
+		Number:   m.Number,
+		Title:    m.Title.Inner,
+		Studio:   m.Studio.Inner,
+		Director: m.Director.Inner,
+		Release:  m.Release,
+		Runtime:  m.RunTime,
+		Series:   m.Set,
+		Tags:     tags,
+		Actors:   m.Actor,
+		Cover:    m.Cover,
+		WebSite:  m.WebSite,
+		Plot:     m.Plot.Inner,
+	}
+}
+
+// 输出表格
+func writeScrapeTable(w io.Writer, out *scrapeOutput) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	// 元数据
+	if m := out.Media; m != nil {
+		// 演员
+		actors := make([]string, 0, len(m.Actors))
+		for _, a := range m.Actors {
+			actors = append(actors, a.Name)
+		}
+
+		_, _ = fmt.Fprintf(tw, "番号:\t%s\n", m.Number)
+		_, _ = fmt.Fprintf(tw, "标题:\t%s\n", m.Title)
+		_, _ = fmt.Fprintf(tw, "厂商:\t%s\n", m.Studio)
+		_, _ = fmt.Fprintf(tw, "导演:\t%s\n", m.Director)
+		_, _ = fmt.Fprintf(tw, "发行时间:\t%s\n", m.Release)
+		_, _ = fmt.Fprintf(tw, "时长:\t%s\n", m.Runtime)
+		_, _ = fmt.Fprintf(tw, "系列:\t%s\n", m.Series)
+		_, _ = fmt.Fprintf(tw, "标签:\t%s\n", strings.Join(m.Tags, ", "))
+		_, _ = fmt.Fprintf(tw, "演员:\t%s\n", strings.Join(actors, ", "))
+		_, _ = fmt.Fprintf(tw, "封面:\t%s\n", m.Cover)
+		_, _ = fmt.Fprintf(tw, "地址:\t%s\n", m.WebSite)
+		_, _ = fmt.Fprintf(tw, "来源:\t%s\n", out.Source)
+		_, _ = fmt.Fprintf(tw, "简介:\t%s\n", strings.ReplaceAll(m.Plot, "\n", " "))
+		_, _ = fmt.Fprintln(tw)
+	}
+	_ = tw.Flush()
+
+	writeAttempts(w, out.Attempts)
+}
+
+// 输出刮削尝试记录
+func writeAttempts(w io.Writer, attempts []scrapeAttempt) {
+	// 没有尝试记录
+	if len(attempts) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "序号\t刮削器\t结果")
+	for i, a := range attempts {
+		// 结果
+		result := "成功"
+		if a.Error != "" {
+			result = "失败: " + a.Error
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", i+1, a.Site, result)
+	}
+
+	_ = tw.Flush()
+}
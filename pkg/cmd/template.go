@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// 测试用文件名称模板
+var templateFilename string
+
+// template命令
+func (e *Executor) initTemplate() {
+	templateCmd := &cobra.Command{
+		Use: "template",
+		Long: `
+路径模板工具`,
+		Example: `  AVMeta template test
+  AVMeta template test "{studio|未知厂商}/{if uncensored}无码{else}有码{end}/{actors:2}/{number}"
+  AVMeta template test "{number}" --filename "{number}{variant} {title:40}"`,
+	}
+
+	testCmd := &cobra.Command{
+		Use: "test [template]",
+		Long: `
+使用示例影片数据渲染路径模板, 未传入模板时使用配置文件中的 path.directory 及 path.filename
+
+模板语法:
+  {name}                      替换为变量内容, 变量或修饰符名称不存在时视为错误
+  {name|默认值}               变量为空时使用默认值
+  {name:40}                   截取前 40 个字符, actors 则取前 40 位演员
+  {name:upper}                转换为大写, lower 转换为小写
+  {if name}...{else}...{end}  变量不为空时输出第一部分, 否则输出 else 部分, 条件前加 ! 表示取反
+
+可用变量:
+  actor actors number release year month studio title series director
+  source resolution variant sub part uncensored`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: e.templateTestRunFunc,
+	}
+
+	// 添加参数
+	testCmd.Flags().StringVar(&templateFilename, "filename", "", "要测试的文件名称模板, 默认使用配置文件中的 path.filename")
+
+	templateCmd.AddCommand(testCmd)
+	e.rootCmd.AddCommand(templateCmd)
+}
+
+// 模板测试执行命令
+func (e *Executor) templateTestRunFunc(cmd *cobra.Command, args []string) error {
+	// 复制配置，避免影响其他命令
+	cfg := *e.cfg
+	if len(args) > 0 {
+		cfg.Path.Directory = args[0]
+	}
+	if templateFilename != "" {
+		cfg.Path.Filename = templateFilename
+	}
+	if cfg.Path.Filename == "" {
+		cfg.Path.Filename = util.DefaultFilename
+	}
+
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "目录模板:\t%s\n", cfg.Path.Directory)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "文件模板:\t%s\n", cfg.Path.Filename)
+
+	// 检查语法及变量名称
+	var failed bool
+	vars := (&media.Media{}).ConvertMap()
+	for _, rule := range []string{cfg.Path.Directory, cfg.Path.Filename} {
+		if err := util.ValidateTemplate(rule, vars); err != nil {
+			cmd.PrintErrf("%s: %s\n", rule, err)
+			failed = true
+		}
+	}
+
+	// 使用示例数据渲染
+	for _, m := range sampleMedias() {
+		data := m.ConvertMap()
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "\n示例影片:\t%s [%s]\n", m.Number, m.Source)
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "保存路径:\t%s\n", util.GetNumberPath(data, &cfg))
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "文件名称:\t%s\n", util.GetFileName(data, &cfg))
+	}
+
+	if failed {
+		return fmt.Errorf("模板错误")
+	}
+
+	return nil
+}
+
+// 示例影片数据，包含有码及无码两部影片
+func sampleMedias() []*media.Media {
+	// 有码影片
+	censored := &media.Media{
+		Number:     "SSIS-001",
+		Title:      media.Inner{Inner: "SSIS-001 新人NO.1STYLE 河北彩花AVデビュー"},
+		Studio:     media.Inner{Inner: "エスワン ナンバーワンスタイル"},
+		Director:   media.Inner{Inner: "紋℃"},
+		Release:    "2021-02-19",
+		Year:       "2021",
+		Month:      "02",
+		Set:        "新人NO.1STYLE",
+		Actor:      []media.Actor{{Name: "河北彩花"}, {Name: "三上悠亜"}, {Name: "葵つかさ"}},
+		Source:     "DMM",
+		Sub:        true,
+		Resolution: "1080p",
+	}
+
+	// 无码影片
+	uncensored := &media.Media{
+		Number:   "010120-001",
+		Title:    media.Inner{Inner: "010120-001 カリビアンコム 新春スペシャル"},
+		Studio:   media.Inner{Inner: "カリビアンコム"},
+		Release:  "2020-01-01",
+		Year:     "2020",
+		Month:    "01",
+		Source:   "CaribBeanCom",
+		NoMosaic: true,
+	}
+
+	return []*media.Media{censored, uncensored}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// 是否列出整理日志
+var undoList bool
+
+// undo命令
+func (e *Executor) initUndo() {
+	undoCmd := &cobra.Command{
+		Use: "undo [run-id]",
+		Long: `
+撤销整理操作, 将影片移回原位置, 删除生成的元数据、图片及空目录, 恢复被覆盖的文件,
+未指定整理编号时撤销最近一次整理`,
+		Example: `  AVMeta undo
+  AVMeta undo 20200101-120000
+  AVMeta undo --list`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: e.undoRunFunc,
+	}
+
+	// 添加参数
+	undoCmd.Flags().BoolVar(&undoList, "list", false, "列出所有可撤销的整理编号")
+	e.rootCmd.AddCommand(undoCmd)
+}
+
+// 撤销执行命令
+func (e *Executor) undoRunFunc(cmd *cobra.Command, args []string) error {
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 获取整理编号列表
+	ids, err := util.Journals()
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 列出整理编号
+	if undoList {
+		for _, id := range ids {
+			// 读取条目
+			entries, err := util.ReadJournal(id)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s\t%d 项操作\n", id, len(entries))
+		}
+
+		return nil
+	}
+
+	// 整理编号
+	var id string
+	if len(args) > 0 {
+		id = args[0]
+	} else {
+		// 没有可撤销的整理
+		if len(ids) == 0 {
+			return fmt.Errorf("没有可撤销的整理")
+		}
+		// 最近一次整理
+		id = ids[len(ids)-1]
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "开始撤销整理 [%s]...\n", id)
+
+	// 撤销
+	return util.UndoJournal(id, func(entry util.JournalEntry, err error) {
+		// 操作描述
+		var desc string
+		switch entry.Op {
+		case util.JournalMove:
+			desc = fmt.Sprintf("移回 %s -> %s", entry.Dst, entry.Src)
+		case util.JournalReplace:
+			desc = fmt.Sprintf("恢复文件 %s", entry.Dst)
+		case util.JournalCreate:
+			desc = fmt.Sprintf("删除文件 %s", entry.Dst)
+		case util.JournalMkdir:
+			desc = fmt.Sprintf("删除目录 %s", entry.Dst)
+		default:
+			desc = fmt.Sprintf("%s %s", entry.Op, entry.Dst)
+		}
+
+		// 保留非空目录
+		if errors.Is(err, util.ErrDirKept) {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "[保留] %s, %s\n", desc, err)
+			return
+		}
+		// 是否失败
+		if err != nil {
+			cmd.PrintErrf("[失败] %s, 错误原因: %s\n", desc, err)
+			return
+		}
+
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "[完成] %s\n", desc)
+	})
+}
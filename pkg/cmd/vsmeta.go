@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册jpeg解码
+	_ "image/png"  // 注册png解码
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/logs"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+var (
+	// 输出格式
+	vsmetaFormat string
+	// 图片导出目录
+	vsmetaImages string
+	// 是否覆盖已存在的 nfo
+	vsmetaOverwrite bool
+)
+
+// vsmeta 解析结果输出结构
+type vsmetaOutput struct {
+	File     string       `json:"file"`
+	Type     int          `json:"type"`
+	Locked   bool         `json:"locked"`
+	Media    *scrapeMedia `json:"media"`
+	Poster   *vsmetaImage `json:"poster,omitempty"`
+	Backdrop *vsmetaImage `json:"backdrop,omitempty"`
+}
+
+// vsmeta 内嵌图片输出结构
+type vsmetaImage struct {
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int    `json:"size"`
+	Error  string `json:"error,omitempty"`
+}
+
+// vsmeta命令
+func (e *Executor) initVSMeta() {
+	vsmetaCmd := &cobra.Command{
+		Use: "vsmeta",
+		Long: `
+群晖 vsmeta 元数据工具`,
+		Example: `  AVMeta vsmeta dump SSIS-001.mp4.vsmeta
+  AVMeta vsmeta dump SSIS-001.mp4.vsmeta --format json
+  AVMeta vsmeta dump SSIS-001.mp4.vsmeta --images ./images
+  AVMeta vsmeta nfo /data/av/success`,
+	}
+
+	dumpCmd := &cobra.Command{
+		Use: "dump <file>",
+		Long: `
+解析 vsmeta 文件并输出其中的元数据及内嵌图片信息, 可用于检查及修复 vsmeta 文件`,
+		Args: cobra.ExactArgs(1),
+		RunE: e.vsmetaDumpRunFunc,
+	}
+	dumpCmd.Flags().StringVar(&vsmetaFormat, "format", "table", "输出格式: table, json, nfo")
+	dumpCmd.Flags().StringVar(&vsmetaImages, "images", "", "将内嵌的封面及背景导出到指定目录")
+
+	nfoCmd := &cobra.Command{
+		Use: "nfo [dir]",
+		Long: `
+将目录下所有 vsmeta 文件转换为 nfo 文件, 未指定目录时使用运行目录 (或 --input 指定目录),
+内嵌的封面及背景将保存为同目录下的 poster.jpg 及 fanart.jpg, 已存在的图片不会被覆盖`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: e.vsmetaNfoRunFunc,
+	}
+	nfoCmd.Flags().BoolVar(&vsmetaOverwrite, "overwrite", false, "覆盖已存在的 nfo 文件")
+
+	vsmetaCmd.AddCommand(dumpCmd, nfoCmd)
+	e.rootCmd.AddCommand(vsmetaCmd)
+}
+
+// 解析执行命令
+func (e *Executor) vsmetaDumpRunFunc(cmd *cobra.Command, args []string) error {
+	// 检查格式
+	switch vsmetaFormat {
+	case "table", "json", "nfo":
+	default:
+		return fmt.Errorf("不支持的输出格式: %s", vsmetaFormat)
+	}
+
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 解析
+	v, err := media.ReadVSMeta(args[0])
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 导出图片
+	if vsmetaImages != "" {
+		if err = util.MkdirAll(vsmetaImages); err != nil {
+			return err
+		}
+		for name, data := range map[string][]byte{"poster.jpg": v.Poster, "fanart.jpg": v.Backdrop} {
+			if len(data) == 0 {
+				continue
+			}
+			if err = util.WriteFile(filepath.Join(vsmetaImages, name), data); err != nil {
+				return err
+			}
+			if name == "poster.jpg" {
+				v.Media.Poster, v.Media.Thumb = name, name
+			} else {
+				v.Media.FanArt = name
+			}
+		}
+	}
+
+	out := vsmetaOutput{
+		File:     args[0],
+		Type:     v.Type,
+		Locked:   v.Locked,
+		Media:    toScrapeMedia(v.Media),
+		Poster:   toVSMetaImage(v.Poster),
+		Backdrop: toVSMetaImage(v.Backdrop),
+	}
+
+	// 输出
+	switch vsmetaFormat {
+	case "json":
+		// 转换为json
+		b, jerr := json.MarshalIndent(out, "", "  ")
+		if jerr != nil {
+			return jerr
+		}
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	case "nfo":
+		// 转换为nfo
+		b, xerr := v.Media.NFO(e.cfg.Media.Profile)
+		if xerr != nil {
+			return xerr
+		}
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	default:
+		writeVSMetaTable(cmd.OutOrStdout(), &out)
+	}
+
+	return nil
+}
+
+// 转换执行命令
+func (e *Executor) vsmetaNfoRunFunc(cmd *cobra.Command, args []string) error {
+	// 获取要转换的目录
+	dir := util.InputPath(e.cfg)
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	// 检查 nfo 格式
+	err := media.CheckProfile(e.cfg.Media.Profile)
+	if err != nil {
+		return err
+	}
+
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 初始化日志
+	logs.Log("")
+
+	// 查找vsmeta
+	var files []string
+	err = filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		// 错误
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && strings.EqualFold(filepath.Ext(file), ".vsmeta") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 输出总量
+	logs.Info("共探索到 %d 个 vsmeta 文件, 开始转换...\n\n", len(files))
+
+	// 转换结果
+	var converted, skipped, failed int
+	for _, file := range files {
+		nfo, err := media.VSMetaToNfo(file, e.cfg, vsmetaOverwrite)
+		switch {
+		case err == media.ErrNfoExists:
+			skipped++
+			logs.Info("文件: [%s] 已存在 nfo 文件, 跳过\n", filepath.Base(file))
+		case err != nil:
+			failed++
+			logs.Error("文件: [%s] 转换失败, 错误原因: %s\n", filepath.Base(file), err)
+		default:
+			converted++
+			logs.Info("文件: [%s] 转换成功, 路径: %s\n", filepath.Base(file), nfo)
+		}
+	}
+
+	logs.Info("共转换 %d 个, 跳过 %d 个, 失败 %d 个", converted, skipped, failed)
+
+	return nil
+}
+
+// 获取图片信息
+func toVSMetaImage(data []byte) *vsmetaImage {
+	// 没有图片
+	if len(data) == 0 {
+		return nil
+	}
+
+	img := &vsmetaImage{Size: len(data)}
+	// 解析图片
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		img.Error = err.Error()
+		return img
+	}
+	img.Format, img.Width, img.Height = format, cfg.Width, cfg.Height
+
+	return img
+}
+
+// 输出表格
+func writeVSMetaTable(w io.Writer, out *vsmetaOutput) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	// 图片信息
+	imageInfo := func(img *vsmetaImage) string {
+		switch {
+		case img == nil:
+			return "无"
+		case img.Error != "":
+			return fmt.Sprintf("%s, 无法解码: %s", formatSize(int64(img.Size)), img.Error)
+		}
+		return fmt.Sprintf("%s %dx%d, %s", img.Format, img.Width, img.Height, formatSize(int64(img.Size)))
+	}
+
+	// 演员
+	m := out.Media
+	actors := make([]string, 0, len(m.Actors))
+	for _, a := range m.Actors {
+		actors = append(actors, a.Name)
+	}
+
+	_, _ = fmt.Fprintf(tw, "文件:\t%s\n", out.File)
+	_, _ = fmt.Fprintf(tw, "类型:\t%d\n", out.Type)
+	_, _ = fmt.Fprintf(tw, "锁定:\t%t\n", out.Locked)
+	_, _ = fmt.Fprintf(tw, "番号:\t%s\n", m.Number)
+	_, _ = fmt.Fprintf(tw, "标题:\t%s\n", m.Title)
+	_, _ = fmt.Fprintf(tw, "厂商:\t%s\n", m.Studio)
+	_, _ = fmt.Fprintf(tw, "导演:\t%s\n", m.Director)
+	_, _ = fmt.Fprintf(tw, "发行时间:\t%s\n", m.Release)
+	_, _ = fmt.Fprintf(tw, "标签:\t%s\n", strings.Join(m.Tags, ", "))
+	_, _ = fmt.Fprintf(tw, "演员:\t%s\n", strings.Join(actors, ", "))
+	_, _ = fmt.Fprintf(tw, "封面:\t%s\n", imageInfo(out.Poster))
+	_, _ = fmt.Fprintf(tw, "背景:\t%s\n", imageInfo(out.Backdrop))
+	_, _ = fmt.Fprintf(tw, "简介:\t%s\n", strings.ReplaceAll(m.Plot, "\n", " "))
+	_ = tw.Flush()
+}
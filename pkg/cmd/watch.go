@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ylqjgm/AVMeta/pkg/logs"
+	"github.com/ylqjgm/AVMeta/pkg/media"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+var (
+	// 是否强制使用轮询
+	watchPoll bool
+	// 检查间隔
+	watchInterval time.Duration
+	// 文件大小保持不变的时长
+	watchStable time.Duration
+	// 失败重试次数
+	watchRetry int
+	// 失败重试间隔
+	watchRetryDelay time.Duration
+)
+
+// watch命令
+func (e *Executor) initWatch() {
+	watchCmd := &cobra.Command{
+		Use: "watch [dir]",
+		Long: `
+监控指定目录, 发现新增的视频文件后等待其下载完成 (文件大小不再变化),
+再自动刮削整理, 整理失败的文件将在一段时间后重试,
+未指定目录时监控 --input 参数或配置文件中 path.input 指定的目录`,
+		Example: `  AVMeta watch /data/downloads
+  AVMeta watch /data/downloads --stable 1m
+  AVMeta watch /mnt/nas/downloads --poll --interval 30s`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: e.watchRunFunc,
+	}
+
+	// 添加参数
+	watchCmd.Flags().BoolVar(&forceRun, "force", false, "忽略整理索引, 重新整理已整理过且未变化的文件")
+	watchCmd.Flags().BoolVar(&watchPoll, "poll", false, "使用轮询方式监控, 适用于不支持文件系统通知的网络目录")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "检查间隔, 轮询方式下同时为扫描间隔")
+	watchCmd.Flags().DurationVar(&watchStable, "stable", 30*time.Second, "文件大小保持不变多久后视为下载完成")
+	watchCmd.Flags().IntVar(&watchRetry, "retry", 3, "整理失败的文件最多重试次数, 0 为不重试")
+	watchCmd.Flags().DurationVar(&watchRetryDelay, "retry-delay", 10*time.Minute, "首次重试间隔, 之后每次重试间隔加倍")
+
+	e.rootCmd.AddCommand(watchCmd)
+}
+
+// 监控执行命令
+func (e *Executor) watchRunFunc(cmd *cobra.Command, args []string) error {
+	// 获取监控目录
+	dir := util.InputPath(e.cfg)
+	if len(args) > 0 {
+		abs, err := filepath.Abs(args[0])
+		// 检查
+		if err != nil {
+			return err
+		}
+		dir = abs
+	}
+	// 检查目录
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: 不是目录", dir)
+	}
+	// 检查间隔
+	if watchInterval <= 0 {
+		return fmt.Errorf("检查间隔必须大于 0")
+	}
+	// 检查整理方式
+	err = util.CheckMode(e.cfg.Path.Mode)
+	if err != nil {
+		return err
+	}
+	// 检查 nfo 格式
+	err = media.CheckProfile(e.cfg.Media.Profile)
+	if err != nil {
+		return err
+	}
+	// 检查保存路径及文件名称模板
+	err = util.CheckTemplate(e.cfg, (&media.Media{}).ConvertMap())
+	if err != nil {
+		return err
+	}
+
+	// 参数正确, 之后的错误不再输出帮助
+	cmd.SilenceUsage = true
+
+	// 初始化日志
+	logs.Log("logs")
+
+	// 获取上下文
+	ctx := cmd.Context()
+
+	// 输出大文件复制进度
+	util.SetProgress(copyProgress)
+	defer util.SetProgress(nil)
+
+	// 读取整理索引
+	e.openIndex()
+
+	// 创建监控
+	w := util.NewWatcher(dir, util.NewFileFilter(dir, e.cfg).Match)
+	// 启用文件系统通知
+	if !watchPoll {
+		if err := w.Notify(); err != nil {
+			logs.Warning("文件系统通知不可用, 使用轮询方式监控, 错误原因: %s", err)
+		}
+	}
+
+	// 整理队列
+	q := &watchQueue{
+		signal:  make(chan struct{}, 1),
+		retries: make(map[string]*watchRetryItem),
+	}
+
+	// 整理进程
+	done := make(chan struct{})
+	go func() {
+		e.watchProcess(ctx, q)
+		close(done)
+	}()
+
+	logs.Info("开始监控目录 [%s], 文件大小 %s 内不再变化时开始整理...", dir, watchStable)
+
+	// 开始监控
+	err = w.Run(ctx, watchInterval, watchStable, q.push)
+
+	// 等待整理结束
+	<-done
+
+	logs.Warning("目录监控已停止")
+
+	return err
+}
+
+// 监控整理队列
+type watchQueue struct {
+	sync.Mutex
+	files   []string                   // 等待整理的文件
+	signal  chan struct{}              // 新文件通知
+	retries map[string]*watchRetryItem // 等待重试的文件
+}
+
+// 重试条目
+type watchRetryItem struct {
+	attempts int       // 已重试次数
+	next     time.Time // 下次重试时间
+}
+
+// 加入下载完成的文件
+func (q *watchQueue) push(files []string) {
+	q.Lock()
+	q.files = append(q.files, files...)
+	q.Unlock()
+
+	// 通知整理进程
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// 取出等待整理的文件及已到重试时间的文件
+func (q *watchQueue) take(now time.Time) (files []string, attempts map[string]int) {
+	q.Lock()
+	defer q.Unlock()
+
+	// 新文件
+	files = q.files
+	q.files = nil
+
+	// 重试文件
+	attempts = make(map[string]int)
+	for file, item := range q.retries {
+		if now.Before(item.next) {
+			continue
+		}
+		delete(q.retries, file)
+		files = append(files, file)
+		attempts[file] = item.attempts
+	}
+
+	return files, attempts
+}
+
+// 加入重试队列
+func (q *watchQueue) retry(file string, attempts int) {
+	// 超过重试次数
+	if attempts > watchRetry {
+		if watchRetry > 0 {
+			logs.Warning("文件 [%s] 已重试 %d 次, 不再重试", path.Base(file), watchRetry)
+		}
+		return
+	}
+
+	// 重试间隔，每次加倍
+	delay := watchRetryDelay << uint(attempts-1)
+
+	q.Lock()
+	q.retries[file] = &watchRetryItem{attempts: attempts, next: time.Now().Add(delay)}
+	q.Unlock()
+
+	logs.Info("文件 [%s] 将于 %s 后第 %d 次重试", path.Base(file), delay, attempts)
+}
+
+// 监控整理进程
+func (e *Executor) watchProcess(ctx context.Context, q *watchQueue) {
+	// 定时检查重试队列
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.signal:
+		case <-ticker.C:
+		}
+
+		// 取出文件
+		files, attempts := q.take(time.Now())
+		if len(files) > 0 {
+			e.watchPack(ctx, q, files, attempts)
+		}
+	}
+}
+
+// 刮削整理一批文件，失败的文件加入重试队列
+func (e *Executor) watchPack(ctx context.Context, q *watchQueue, files []string, attempts map[string]int) {
+	// 创建整理日志，用于撤销本批整理
+	journal, err := util.NewJournal()
+	// 检查
+	if err != nil {
+		logs.Warning("整理日志创建失败, 本批整理将无法撤销, 错误原因: %s", err)
+	} else {
+		util.SetJournal(journal)
+		defer func() {
+			util.SetJournal(nil)
+			_ = journal.Close()
+		}()
+	}
+
+	// 跳过已整理的文件
+	files, stats := e.filterIndexed(files)
+	if stats[util.IndexUnchanged] > 0 {
+		logIndexStats(stats)
+	}
+	if len(files) == 0 {
+		return
+	}
+	// 保存整理索引
+	defer e.saveIndex()
+
+	// 合并分段视频
+	videos := util.GroupVideos(files)
+
+	logs.Info("\n\n发现 %d 个视频文件, 共 %d 部影片, 开始刮削整理...\n\n", len(files), len(videos))
+	if journal != nil {
+		logs.Info("本批整理编号 [%s], 可使用 AVMeta undo %s 撤销本批整理", journal.ID(), journal.ID())
+	}
+
+	// 初始化进程
+	wg := util.NewWaitGroup(2)
+
+	// 循环视频列表
+	for _, v := range videos {
+		// 计数加
+		wg.AddDelta()
+		// 是否已中断
+		if ctx.Err() != nil {
+			wg.Done()
+			break
+		}
+
+		go func(v util.Video) {
+			defer wg.Done()
+
+			// 刮削整理
+			if e.packVideo(ctx, v) == nil || ctx.Err() != nil {
+				return
+			}
+
+			// 加入重试队列
+			for _, f := range v.Files() {
+				// 移动方式下文件已位于失败目录
+				file := f
+				if e.cfg.Path.Mode == "" || e.cfg.Path.Mode == util.ModeMove {
+					file = util.FailPath(f, e.cfg)
+				}
+				q.retry(file, attempts[f]+1)
+			}
+		}(v)
+	}
+
+	// 等待结束
+	wg.Wait()
+}
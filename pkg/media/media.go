@@ -3,43 +3,70 @@ package media
 import (
 	"encoding/xml"
 	"fmt"
+	"math"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ylqjgm/AVMeta/pkg/logs"
+	"github.com/ylqjgm/AVMeta/pkg/probe"
 	"github.com/ylqjgm/AVMeta/pkg/scraper"
+	"github.com/ylqjgm/AVMeta/pkg/util"
 )
 
 // Media Nfo信息结构，
 // 用以存储 nfo 文件所需各项信息。
 type Media struct {
-	XMLName   xml.Name `xml:"movie"`
-	Title     Inner    `xml:"title"`
-	SortTitle string   `xml:"sorttitle"`
-	Number    string   `xml:"num"`
-	Studio    Inner    `xml:"studio"`
-	Maker     Inner    `xml:"maker"`
-	Director  Inner    `xml:"director"`
-	Release   string   `xml:"release"`
-	Premiered string   `xml:"premiered"`
-	Year      string   `xml:"year"`
-	Plot      Inner    `xml:"plot"`
-	Outline   Inner    `xml:"outline"`
-	RunTime   string   `xml:"runtime"`
-	Mpaa      string   `xml:"mpaa"`
-	Country   string   `xml:"country"`
-	Poster    string   `xml:"poster"`
-	Thumb     string   `xml:"thumb"`
-	FanArt    string   `xml:"fanart"`
-	Actor     []Actor  `xml:"actor"`
-	Tag       []Inner  `xml:"tag"`
-	Genre     []Inner  `xml:"genre"`
-	Set       string   `xml:"set"`
-	Label     string   `xml:"label"`
-	Cover     string   `xml:"cover"`
-	WebSite   string   `xml:"website"`
-	Month     string   `xml:"-"`
-	DirPath   string   `xml:"-"`
-	Source    string   `xml:"-"`
+	XMLName   xml.Name  `xml:"movie"`
+	Title     Inner     `xml:"title"`
+	SortTitle string    `xml:"sorttitle"`
+	Number    string    `xml:"num"`
+	Studio    Inner     `xml:"studio"`
+	Maker     Inner     `xml:"maker"`
+	Director  Inner     `xml:"director"`
+	Release   string    `xml:"release"`
+	Premiered string    `xml:"premiered"`
+	Year      string    `xml:"year"`
+	Plot      Inner     `xml:"plot"`
+	Outline   Inner     `xml:"outline"`
+	RunTime   string    `xml:"runtime"`
+	Mpaa      string    `xml:"mpaa"`
+	Country   string    `xml:"country"`
+	Poster    string    `xml:"poster"`
+	Thumb     string    `xml:"thumb"`
+	FanArt    string    `xml:"fanart"`
+	Actor     []Actor   `xml:"actor"`
+	Tag       []Inner   `xml:"tag"`
+	Genre     []Inner   `xml:"genre"`
+	Set       string    `xml:"set"`
+	Label     string    `xml:"label"`
+	Cover     string    `xml:"cover"`
+	WebSite   string    `xml:"website"`
+	FileInfo  *FileInfo `xml:"fileinfo,omitempty"`
+	// 媒体库锁定标识，锁定后刷新元数据时将跳过整个影片或指定字段
+	LockData     bool   `xml:"lockdata,omitempty"`
+	LockedFields string `xml:"lockedfields,omitempty"`
+	Month        string `xml:"-"`
+	DirPath      string `xml:"-"`
+	Source       string `xml:"-"`
+	// 各字段实际来源，仅在多源合并时记录
+	Sources map[string]string `xml:"-"`
+	// 文件名中的版本标识
+	Sub        bool `xml:"-"`
+	Uncensored bool `xml:"-"`
+	UHD        bool `xml:"-"`
+	// 是否为无码影片，由刮削来源判断
+	NoMosaic bool `xml:"-"`
+	// 编剧，写入 nfo 的 credits，仅部分刮削来源提供
+	Writer string `xml:"-"`
+	// 评分，满分 10 分，及评分人数
+	Rating float64 `xml:"-"`
+	Votes  int     `xml:"-"`
+	// 分辨率标识，如 1080p，由视频文件读取
+	Resolution string `xml:"-"`
 }
 
 // Inner 文字数据，为了避免某些内容被转义。
@@ -47,10 +74,45 @@ type Inner struct {
 	Inner string `xml:",innerxml"`
 }
 
-// Actor 演员信息，保存演员姓名及头像地址。
+// Actor 演员信息，保存演员姓名、头像地址及角色，
+// 刮削来源没有角色信息，角色来自 nfo 中已有的内容。
 type Actor struct {
 	Name  string `xml:"name"`
 	Thumb string `xml:"thumb"`
+	Role  string `xml:"role,omitempty"`
+}
+
+// FileInfo 文件信息，记录视频流、音频流及外挂字幕。
+type FileInfo struct {
+	StreamDetails StreamDetails `xml:"streamdetails"`
+}
+
+// StreamDetails 媒体流信息。
+type StreamDetails struct {
+	Video    []VideoDetail `xml:"video"`
+	Audio    []AudioDetail `xml:"audio"`
+	Subtitle []Subtitle    `xml:"subtitle"`
+}
+
+// VideoDetail 视频流信息。
+type VideoDetail struct {
+	Codec    string `xml:"codec,omitempty"`
+	Aspect   string `xml:"aspect,omitempty"`
+	Width    int    `xml:"width,omitempty"`
+	Height   int    `xml:"height,omitempty"`
+	Duration int    `xml:"durationinseconds,omitempty"`
+}
+
+// AudioDetail 音频流信息。
+type AudioDetail struct {
+	Codec    string `xml:"codec,omitempty"`
+	Language string `xml:"language,omitempty"`
+	Channels int    `xml:"channels,omitempty"`
+}
+
+// Subtitle 字幕信息。
+type Subtitle struct {
+	Language string `xml:"language,omitempty"`
 }
 
 // ParseMedia 将刮削对象解析为 Media 结构体，
@@ -82,6 +144,11 @@ func ParseMedia(s scraper.IScraper, site string) (*Media, error) {
 		})
 	}
 
+	// 按姓名排序，保证输出顺序一致
+	sort.Slice(actors, func(i, j int) bool {
+		return actors[i].Name < actors[j].Name
+	})
+
 	// 短标题
 	m.SortTitle = strings.TrimSpace(s.GetNumber())
 	// 番号
@@ -120,6 +187,10 @@ func ParseMedia(s scraper.IScraper, site string) (*Media, error) {
 	m.Genre = m.Tag
 	// 系列
 	m.Set = strings.TrimSpace(s.GetSeries())
+	// 评分
+	m.Rating, m.Votes = scraper.GetRating(s)
+	// 编剧
+	m.Writer = strings.TrimSpace(scraper.GetWriter(s))
 	// 图片
 	m.Cover = strings.TrimSpace(s.GetCover())
 	// 地址
@@ -136,9 +207,150 @@ func ParseMedia(s scraper.IScraper, site string) (*Media, error) {
 	// 设置标题
 	m.Title = Inner{Inner: title}
 
+	// 记录多源合并时各字段来源
+	if ms, ok := s.(*scraper.MergeScraper); ok {
+		m.Sources = ms.Sources()
+	}
+
+	// 是否为无码来源
+	for _, name := range strings.Split(site, "+") {
+		if e, ok := scraper.Get(name, nil); ok && e.Uncensored {
+			m.NoMosaic = true
+		}
+	}
+
 	return &m, nil
 }
 
+// SetVariant 设置文件名中的版本标识，并将其加入标签。
+//
+// info CodeInfo结构体，传入番号提取结果。
+func (m *Media) SetVariant(info util.CodeInfo) {
+	m.Sub = info.Sub
+	m.Uncensored = info.Uncensored
+	m.UHD = info.UHD
+
+	// 版本标签
+	var tags []string
+	if info.Sub {
+		tags = append(tags, "中文字幕")
+	}
+	if info.Uncensored {
+		tags = append(tags, "无码破解")
+	}
+	if info.UHD {
+		tags = append(tags, "4K")
+	}
+
+	// 加入标签，忽略已存在的标签
+	for _, tag := range tags {
+		exists := false
+		for _, t := range m.Tag {
+			if t.Inner == tag {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			m.Tag = append(m.Tag, Inner{Inner: tag})
+		}
+	}
+	// 类型
+	m.Genre = m.Tag
+}
+
+// 将视频的外挂字幕记录到文件信息中，相同语言只记录一次
+func (m *Media) addSubtitles(v util.Video) {
+	// 已记录的语言
+	added := make(map[string]bool)
+
+	// 循环分段
+	for _, part := range v.Parts {
+		for _, sc := range util.FindSidecars(part.File) {
+			// .idx 与 .sub 为同一字幕
+			if strings.EqualFold(path.Ext(sc.File), ".idx") {
+				continue
+			}
+			// 是否已记录
+			lang := strings.ToLower(sc.Lang)
+			if added[lang] {
+				continue
+			}
+			added[lang] = true
+
+			// 初始化文件信息
+			if m.FileInfo == nil {
+				m.FileInfo = &FileInfo{}
+			}
+			m.FileInfo.StreamDetails.Subtitle = append(m.FileInfo.StreamDetails.Subtitle, Subtitle{Language: lang})
+		}
+	}
+}
+
+// 读取视频文件信息，记录视频流及音频流，刮削结果没有时长时使用视频时长，
+// 分段视频的时长为各分段之和。
+func (m *Media) addStreams(v util.Video) {
+	// 总时长
+	var total time.Duration
+	// 是否所有分段均读取成功
+	complete := true
+	// 是否已记录流信息
+	recorded := false
+
+	// 循环分段
+	for _, part := range v.Parts {
+		// 读取视频信息
+		info, err := probe.File(part.File)
+		// 检查
+		if err != nil {
+			if err != probe.ErrUnsupported {
+				logs.Warning("文件 [%s] 视频信息读取失败, 错误原因: %s", path.Base(part.File), err)
+			}
+			complete = false
+			continue
+		}
+		total += info.Duration
+
+		// 流信息使用第一个读取成功的分段
+		if recorded {
+			continue
+		}
+		recorded = true
+
+		// 初始化文件信息
+		if m.FileInfo == nil {
+			m.FileInfo = &FileInfo{}
+		}
+		for _, s := range info.Video {
+			d := VideoDetail{Codec: s.Codec, Width: s.Width, Height: s.Height}
+			if s.Width > 0 && s.Height > 0 {
+				d.Aspect = strconv.FormatFloat(float64(s.Width)/float64(s.Height), 'f', 2, 64)
+			}
+			m.FileInfo.StreamDetails.Video = append(m.FileInfo.StreamDetails.Video, d)
+		}
+		for _, s := range info.Audio {
+			m.FileInfo.StreamDetails.Audio = append(m.FileInfo.StreamDetails.Audio, AudioDetail{Codec: s.Codec, Language: s.Language, Channels: s.Channels})
+		}
+		m.Resolution = info.Resolution()
+	}
+
+	// 视频时长
+	if complete && total > 0 && m.FileInfo != nil {
+		for i := range m.FileInfo.StreamDetails.Video {
+			m.FileInfo.StreamDetails.Video[i].Duration = int(total.Seconds())
+		}
+	}
+	// 刮削结果没有时长
+	if complete && total > 0 && strings.TrimSpace(m.RunTime) == "" {
+		m.RunTime = strconv.Itoa(int(math.Round(total.Minutes())))
+	}
+}
+
+// Variant 获取版本标识，如 -C、-UC-4K，没有则返回空。
+func (m *Media) Variant() string {
+	return util.CodeInfo{Sub: m.Sub, Uncensored: m.Uncensored, UHD: m.UHD}.Variant()
+}
+
 // GetYear 通过获取到的发行日期获取年份信息。
 //
 // date 字符串参数，传入发行日期。
@@ -197,6 +409,28 @@ func (m *Media) ConvertMap() map[string]string {
 	replaceMap["{studio}"] = m.Studio.Inner
 	// 替换影片名称
 	replaceMap["{title}"] = m.Title.Inner
+	// 替换版本标识
+	replaceMap["{variant}"] = m.Variant()
+	// 替换字幕标识
+	replaceMap["{sub}"] = ""
+	if m.Sub {
+		replaceMap["{sub}"] = "-C"
+	}
+	// 替换分段标识，由整理时按分段设置
+	replaceMap["{part}"] = ""
+	// 替换系列
+	replaceMap["{series}"] = m.Set
+	// 替换导演
+	replaceMap["{director}"] = m.Director.Inner
+	// 替换刮削来源
+	replaceMap["{source}"] = m.Source
+	// 替换分辨率
+	replaceMap["{resolution}"] = m.Resolution
+	// 是否无码，用于条件判断
+	replaceMap["{uncensored}"] = ""
+	if m.NoMosaic || m.Uncensored {
+		replaceMap["{uncensored}"] = "无码"
+	}
 
 	return replaceMap
 }
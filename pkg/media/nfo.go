@@ -0,0 +1,374 @@
+package media
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nfo 兼容格式
+const (
+	ProfileEmby     = "emby"     // Emby 格式，保留原有的非标准字段，兼容已有媒体库
+	ProfileKodi     = "kodi"     // Kodi 标准格式
+	ProfileJellyfin = "jellyfin" // Jellyfin 格式，在 Kodi 格式基础上补充 Jellyfin 读取的字段
+)
+
+// uniqueid 中番号的类型名称，与 Emby 格式的 <num> 节点同名。
+// Kodi 及 Jellyfin 按 type 属性原样保存任意类型的 uniqueid（Jellyfin 保存为同名的 ProviderId），
+// 不使用 imdb、tmdb 等已有类型，避免番号被当作这些网站的编号生成错误的链接或被刮削器覆盖。
+const uniqueIDType = "num"
+
+// CheckProfile 检查 nfo 格式是否受支持
+//
+// profile 字符串参数，传入 nfo 格式。
+func CheckProfile(profile string) error {
+	switch profile {
+	case "", ProfileEmby, ProfileKodi, ProfileJellyfin:
+		return nil
+	}
+
+	return fmt.Errorf("不支持的 nfo 格式: %s, 可选: emby, kodi, jellyfin", profile)
+}
+
+// nfo 文件结构，写入时按格式填写字段，读取时兼容所有格式
+type nfoMovie struct {
+	XMLName      xml.Name      `xml:"movie"`
+	Title        Inner         `xml:"title"`
+	SortTitle    string        `xml:"sorttitle,omitempty"`
+	Number       string        `xml:"num,omitempty"`
+	UniqueID     []nfoUniqueID `xml:"uniqueid"`
+	Rating       string        `xml:"rating,omitempty"`
+	Ratings      *nfoRatings   `xml:"ratings,omitempty"`
+	Tagline      *Inner        `xml:"tagline,omitempty"`
+	Plot         Inner         `xml:"plot"`
+	Outline      Inner         `xml:"outline"`
+	RunTime      string        `xml:"runtime,omitempty"`
+	Mpaa         string        `xml:"mpaa,omitempty"`
+	Country      string        `xml:"country,omitempty"`
+	Premiered    string        `xml:"premiered,omitempty"`
+	ReleaseDate  string        `xml:"releasedate,omitempty"`
+	Release      string        `xml:"release,omitempty"`
+	Year         string        `xml:"year,omitempty"`
+	Studio       Inner         `xml:"studio"`
+	Maker        *Inner        `xml:"maker,omitempty"`
+	Label        string        `xml:"label,omitempty"`
+	Director     Inner         `xml:"director"`
+	Credits      []string      `xml:"credits"`
+	Set          *nfoSet       `xml:"set,omitempty"`
+	Genre        []Inner       `xml:"genre"`
+	Tag          []Inner       `xml:"tag"`
+	Actor        []nfoActor    `xml:"actor"`
+	Poster       string        `xml:"poster,omitempty"`
+	Thumb        []nfoThumb    `xml:"thumb"`
+	FanArt       *nfoFanArt    `xml:"fanart,omitempty"`
+	Art          *nfoArt       `xml:"art,omitempty"`
+	Cover        string        `xml:"cover,omitempty"`
+	WebSite      string        `xml:"website,omitempty"`
+	FileInfo     *FileInfo     `xml:"fileinfo,omitempty"`
+	LockData     bool          `xml:"lockdata,omitempty"`
+	LockedFields string        `xml:"lockedfields,omitempty"`
+}
+
+// 唯一标识
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+// 评分列表
+type nfoRatings struct {
+	Rating []nfoRating `xml:"rating"`
+}
+
+// 评分
+type nfoRating struct {
+	Name    string `xml:"name,attr,omitempty"`
+	Max     int    `xml:"max,attr,omitempty"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:"value"`
+	Votes   int    `xml:"votes,omitempty"`
+}
+
+// 系列，Emby 格式为文字，Kodi 格式为 name 节点
+type nfoSet struct {
+	Name  string `xml:"name,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// 背景图，Emby 格式为文字，Kodi 格式为 thumb 节点
+type nfoFanArt struct {
+	Thumb []string `xml:"thumb"`
+	Value string   `xml:",chardata"`
+}
+
+// 图片，aspect 为图片类型
+type nfoThumb struct {
+	Aspect string `xml:"aspect,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+// Jellyfin 图片节点
+type nfoArt struct {
+	Poster string `xml:"poster,omitempty"`
+	FanArt string `xml:"fanart,omitempty"`
+}
+
+// 演员
+type nfoActor struct {
+	Name  string `xml:"name"`
+	Role  string `xml:"role,omitempty"`
+	Order int    `xml:"order"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+// 按格式转换为 nfo 文件结构
+func newNfoMovie(m *Media, profile string) (*nfoMovie, error) {
+	// 检查格式
+	if err := CheckProfile(profile); err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = ProfileEmby
+	}
+	emby := profile == ProfileEmby
+
+	// 各格式通用字段
+	n := &nfoMovie{
+		Title:     m.Title,
+		SortTitle: m.SortTitle,
+		Plot:      m.Plot,
+		Outline:   m.Outline,
+		RunTime:   m.RunTime,
+		Mpaa:      m.Mpaa,
+		Country:   m.Country,
+		Premiered: m.Premiered,
+		Year:      m.Year,
+		Studio:    m.Studio,
+		Director:  m.Director,
+		Genre:     m.Genre,
+		Tag:       m.Tag,
+		FileInfo:  m.FileInfo,
+	}
+	// 发行日期，旧数据可能只有 release
+	if n.Premiered == "" {
+		n.Premiered = m.Release
+	}
+
+	// 番号
+	if m.Number != "" {
+		n.UniqueID = []nfoUniqueID{{Type: uniqueIDType, Default: true, Value: m.Number}}
+	}
+
+	// 评分
+	if m.Rating > 0 {
+		rating := strconv.FormatFloat(m.Rating, 'f', 1, 64)
+		n.Ratings = &nfoRatings{Rating: []nfoRating{{
+			Name:    strings.ToLower(m.Source),
+			Max:     10,
+			Default: true,
+			Value:   rating,
+			Votes:   m.Votes,
+		}}}
+		if profile != ProfileKodi {
+			n.Rating = rating
+		}
+	}
+
+	// 标语，为去除番号后的标题
+	if tagline := strings.TrimSpace(strings.TrimPrefix(m.Title.Inner, m.Number)); tagline != "" {
+		n.Tagline = &Inner{Inner: tagline}
+	}
+
+	// 演员
+	for i, a := range m.Actor {
+		n.Actor = append(n.Actor, nfoActor{Name: a.Name, Role: a.Role, Order: i, Thumb: a.Thumb})
+	}
+
+	// 编剧
+	if m.Writer != "" {
+		n.Credits = []string{m.Writer}
+	}
+
+	// 海报
+	if m.Thumb != "" {
+		n.Thumb = []nfoThumb{{Aspect: "poster", Value: m.Thumb}}
+	}
+	// 封面地址，Kodi 格式中为横版图片
+	if !emby && m.Cover != "" {
+		n.Thumb = append(n.Thumb, nfoThumb{Aspect: "landscape", Value: m.Cover})
+	}
+
+	// 系列及背景图
+	if emby {
+		if m.Set != "" {
+			n.Set = &nfoSet{Value: m.Set}
+		}
+		n.FanArt = &nfoFanArt{Value: m.FanArt}
+	} else {
+		if m.Set != "" {
+			n.Set = &nfoSet{Name: m.Set}
+		}
+		if m.FanArt != "" {
+			n.FanArt = &nfoFanArt{Thumb: []string{m.FanArt}}
+		}
+	}
+
+	// Emby 格式保留原有字段
+	if emby {
+		n.Number = m.Number
+		n.Release = m.Release
+		n.Maker = &Inner{Inner: m.Maker.Inner}
+		n.Label = m.Label
+		n.Poster = m.Poster
+		n.Cover = m.Cover
+		n.WebSite = m.WebSite
+	}
+
+	// Jellyfin 格式补充字段
+	if profile == ProfileJellyfin {
+		n.ReleaseDate = n.Premiered
+		if m.Poster != "" || m.FanArt != "" {
+			n.Art = &nfoArt{Poster: m.Poster, FanArt: m.FanArt}
+		}
+	}
+
+	// 锁定标识，Kodi 不支持
+	if profile != ProfileKodi {
+		n.LockData = m.LockData
+		n.LockedFields = m.LockedFields
+	}
+
+	return n, nil
+}
+
+// 转换为 Media 结构体，兼容所有格式
+func (n *nfoMovie) media() *Media {
+	m := &Media{
+		Title:        n.Title,
+		SortTitle:    n.SortTitle,
+		Number:       n.Number,
+		Studio:       n.Studio,
+		Director:     n.Director,
+		Release:      n.Release,
+		Premiered:    n.Premiered,
+		Year:         n.Year,
+		Plot:         n.Plot,
+		Outline:      n.Outline,
+		RunTime:      n.RunTime,
+		Mpaa:         n.Mpaa,
+		Country:      n.Country,
+		Poster:       n.Poster,
+		Tag:          n.Tag,
+		Genre:        n.Genre,
+		Label:        n.Label,
+		Cover:        n.Cover,
+		WebSite:      n.WebSite,
+		FileInfo:     n.FileInfo,
+		LockData:     n.LockData,
+		LockedFields: n.LockedFields,
+	}
+
+	// 番号
+	for _, id := range n.UniqueID {
+		if m.Number == "" || (id.Default && strings.EqualFold(id.Type, uniqueIDType)) {
+			m.Number = strings.TrimSpace(id.Value)
+		}
+	}
+
+	// 发行日期
+	if m.Release == "" {
+		m.Release = m.Premiered
+	}
+	if m.Release == "" {
+		m.Release = n.ReleaseDate
+	}
+	if m.Premiered == "" {
+		m.Premiered = m.Release
+	}
+	// 月份不会写入 nfo
+	m.Month = GetMonth(m.Release)
+
+	// 制作商
+	m.Maker = m.Studio
+	if n.Maker != nil {
+		m.Maker = *n.Maker
+	}
+
+	// 系列
+	if n.Set != nil {
+		m.Set = strings.TrimSpace(n.Set.Name)
+		if m.Set == "" {
+			m.Set = strings.TrimSpace(n.Set.Value)
+		}
+	}
+
+	// 海报
+	for _, t := range n.Thumb {
+		switch {
+		case t.Aspect == "landscape":
+			if m.Cover == "" {
+				m.Cover = strings.TrimSpace(t.Value)
+			}
+		case m.Thumb == "" || t.Aspect == "poster":
+			m.Thumb = strings.TrimSpace(t.Value)
+		}
+	}
+	if m.Poster == "" && n.Art != nil {
+		m.Poster = n.Art.Poster
+	}
+	if m.Poster == "" {
+		m.Poster = m.Thumb
+	}
+
+	// 背景图
+	if n.FanArt != nil {
+		m.FanArt = strings.TrimSpace(n.FanArt.Value)
+		if len(n.FanArt.Thumb) > 0 {
+			m.FanArt = strings.TrimSpace(n.FanArt.Thumb[0])
+		}
+	}
+	if m.FanArt == "" && n.Art != nil {
+		m.FanArt = n.Art.FanArt
+	}
+
+	// 演员，按顺序排列
+	actors := append([]nfoActor(nil), n.Actor...)
+	sort.SliceStable(actors, func(i, j int) bool {
+		return actors[i].Order < actors[j].Order
+	})
+	for _, a := range actors {
+		m.Actor = append(m.Actor, Actor{Name: a.Name, Thumb: a.Thumb, Role: strings.TrimSpace(a.Role)})
+	}
+
+	// 编剧，多个 credits 时使用第一个
+	for _, c := range n.Credits {
+		if c = strings.TrimSpace(c); c != "" {
+			m.Writer = c
+			break
+		}
+	}
+
+	// 评分
+	m.Rating, _ = strconv.ParseFloat(strings.TrimSpace(n.Rating), 64)
+	if n.Ratings != nil {
+		for i, r := range n.Ratings.Rating {
+			if i > 0 && !r.Default {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(r.Value), 64)
+			if err != nil {
+				continue
+			}
+			// 统一为 10 分制
+			if r.Max > 0 && r.Max != 10 {
+				value = value * 10 / float64(r.Max)
+			}
+			m.Rating, m.Votes = value, r.Votes
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,102 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+// 测试用元数据，包含 nfo 中保存的所有字段
+func nfoMedia() *Media {
+	return &Media{
+		Title:     Inner{Inner: "SSIS-001 新人NO.1STYLE 河北彩花AVデビュー"},
+		SortTitle: "SSIS-001",
+		Number:    "SSIS-001",
+		Studio:    Inner{Inner: "エスワン ナンバーワンスタイル"},
+		Maker:     Inner{Inner: "エスワン"},
+		Director:  Inner{Inner: "紋℃"},
+		Writer:    "テスト脚本",
+		Release:   "2021-02-19",
+		Premiered: "2021-02-19",
+		Year:      "2021",
+		Month:     "02",
+		Plot:      Inner{Inner: "新人デビュー作品。"},
+		Outline:   Inner{Inner: "新人デビュー作品。"},
+		RunTime:   "150",
+		Mpaa:      "XXX",
+		Country:   "日本",
+		Poster:    "poster.jpg",
+		Thumb:     "poster.jpg",
+		FanArt:    "fanart.jpg",
+		Actor:     []Actor{{Name: "河北彩花", Thumb: "https://example.com/a.jpg", Role: "新人"}, {Name: "紗倉まな"}},
+		Tag:       []Inner{{Inner: "中文字幕"}},
+		Genre:     []Inner{{Inner: "デビュー作品"}, {Inner: "単体作品"}},
+		Set:       "新人NO.1STYLE",
+		Label:     "S1 NO.1 STYLE",
+		Cover:     "https://example.com/cover.jpg",
+		WebSite:   "https://example.com/SSIS-001",
+		FileInfo: &FileInfo{StreamDetails: StreamDetails{
+			Video: []VideoDetail{{Codec: "h264", Width: 1920, Height: 1080, Duration: 9000}},
+			Audio: []AudioDetail{{Codec: "aac", Language: "jpn", Channels: 2}},
+		}},
+		LockData:     true,
+		LockedFields: "Name|Overview",
+		Rating:       8.5,
+		Votes:        120,
+	}
+}
+
+func TestNfoRoundTrip(t *testing.T) {
+	tests := []struct {
+		profile string
+		// 按格式调整读取后的预期结果
+		want func(m *Media)
+	}{
+		{profile: ProfileEmby, want: func(m *Media) {}},
+		{profile: ProfileKodi, want: func(m *Media) {
+			// 不保存非标准字段及锁定标识，海报使用 thumb
+			m.Maker = m.Studio
+			m.Label, m.WebSite = "", ""
+			m.LockData, m.LockedFields = false, ""
+		}},
+		{profile: ProfileJellyfin, want: func(m *Media) {
+			// 不保存非标准字段
+			m.Maker = m.Studio
+			m.Label, m.WebSite = "", ""
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			b, err := mediaToXML(nfoMedia(), tt.profile)
+			if err != nil {
+				t.Fatalf("mediaToXML() error = %v", err)
+			}
+
+			// 编剧及角色
+			for _, tag := range []string{"<credits>テスト脚本</credits>", "<role>新人</role>"} {
+				if !bytes.Contains(b, []byte(tag)) {
+					t.Errorf("输出不包含 %s", tag)
+				}
+			}
+			// 没有角色的演员不输出 role
+			if n := bytes.Count(b, []byte("<role>")); n != 1 {
+				t.Errorf("<role> 数量 = %d, want 1", n)
+			}
+
+			// 读取
+			var n nfoMovie
+			if err = xml.Unmarshal(b, &n); err != nil {
+				t.Fatalf("xml.Unmarshal() error = %v", err)
+			}
+			got := n.media()
+
+			want := nfoMedia()
+			tt.want(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("media() = %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
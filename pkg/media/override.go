@@ -0,0 +1,257 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+	"gopkg.in/yaml.v2"
+)
+
+// 手动指定文件名称，与视频位于同一目录，按顺序查找
+var overrideFiles = []string{"override.yaml", "override.yml", "override.csv"}
+
+// OverrideSource 仅使用手动指定数据时的刮削来源名称
+const OverrideSource = "override"
+
+// Override 手动指定的番号、刮削来源及元数据，
+// 番号及来源在刮削前生效，其余字段在刮削后覆盖刮削结果。
+type Override struct {
+	Code    string   `yaml:"code"`    // 番号
+	Site    string   `yaml:"site"`    // 刮削来源
+	Title   string   `yaml:"title"`   // 标题
+	Actors  []string `yaml:"actors"`  // 演员
+	Release string   `yaml:"release"` // 发行日期
+	Cover   string   `yaml:"cover"`   // 封面地址
+}
+
+// LoadOverride 读取视频所在目录中的手动指定文件，
+// 并按文件名称（含或不含扩展名）或番号查找对应条目，名称比较不区分大小写，
+// 没有手动指定文件或没有对应条目时返回 nil。
+//
+// file 字符串参数，传入视频文件路径，
+// code 字符串参数，传入从文件名称提取到的番号。
+func LoadOverride(file, code string) (*Override, error) {
+	// 查找手动指定文件
+	dir := filepath.Dir(file)
+	for _, name := range overrideFiles {
+		manifest := filepath.Join(dir, name)
+		if !util.Exists(manifest) {
+			continue
+		}
+
+		// 读取
+		items, err := readOverride(manifest)
+		// 检查
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", manifest, err)
+		}
+
+		// 查找条目
+		base := filepath.Base(file)
+		for _, key := range []string{base, strings.TrimSuffix(base, filepath.Ext(base)), code} {
+			if o, ok := items[strings.ToLower(strings.TrimSpace(key))]; ok {
+				return o, nil
+			}
+		}
+
+		return nil, nil
+	}
+
+	return nil, nil
+}
+
+// 读取手动指定文件，返回以小写名称为键的条目
+func readOverride(file string) (map[string]*Override, error) {
+	// 读取文件
+	b, err := util.ReadFile(file)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	// 条目
+	var items map[string]*Override
+	// 按扩展名解析
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		items, err = parseOverrideCSV(b)
+	} else {
+		err = yaml.UnmarshalStrict(b, &items)
+	}
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	// 统一键名
+	result := make(map[string]*Override, len(items))
+	for key, o := range items {
+		if o != nil {
+			result[strings.ToLower(strings.TrimSpace(key))] = o
+		}
+	}
+
+	return result, nil
+}
+
+// 解析 csv 格式的手动指定文件，
+// 第一行为表头，match 列为文件名称或番号，其余列与 yaml 字段相同，多个演员以 "," 分隔。
+func parseOverrideCSV(b []byte) (map[string]*Override, error) {
+	// 去除 BOM
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	// 读取表头
+	header, err := r.Read()
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["match"]; !ok {
+		return nil, fmt.Errorf("缺少 match 列")
+	}
+	for name := range columns {
+		switch name {
+		case "match", "code", "site", "title", "actors", "release", "cover":
+		default:
+			return nil, fmt.Errorf("未知的列: %s", name)
+		}
+	}
+
+	// 读取条目
+	items := make(map[string]*Override)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// 获取列内容
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		// 跳过空行
+		match := get("match")
+		if match == "" {
+			continue
+		}
+
+		o := &Override{
+			Code:    get("code"),
+			Site:    get("site"),
+			Title:   get("title"),
+			Release: get("release"),
+			Cover:   get("cover"),
+		}
+		for _, actor := range strings.Split(get("actors"), ",") {
+			if actor = strings.TrimSpace(actor); actor != "" {
+				o.Actors = append(o.Actors, actor)
+			}
+		}
+		items[match] = o
+	}
+
+	return items, nil
+}
+
+// 是否可以不经刮削直接使用手动指定数据
+func (o *Override) complete() bool {
+	return o.Title != "" && o.Cover != ""
+}
+
+// 使用手动指定的字段覆盖刮削结果
+func (o *Override) apply(m *Media) {
+	// 标题，与刮削结果一致，以番号开头
+	if o.Title != "" {
+		title := strings.TrimSpace(strings.ReplaceAll(o.Title, m.Number, ""))
+		m.Title = Inner{Inner: strings.TrimSpace(m.Number + " " + title)}
+	}
+
+	// 演员，保留已刮削到的头像
+	if len(o.Actors) > 0 {
+		thumbs := make(map[string]string)
+		for _, a := range m.Actor {
+			thumbs[a.Name] = a.Thumb
+		}
+		m.Actor = nil
+		for _, name := range o.Actors {
+			m.Actor = append(m.Actor, Actor{Name: name, Thumb: thumbs[name]})
+		}
+	}
+
+	// 发行日期
+	if o.Release != "" {
+		m.Release = strings.ReplaceAll(o.Release, "/", "-")
+		m.Premiered = m.Release
+		m.Year = GetYear(m.Release)
+		m.Month = GetMonth(m.Release)
+	}
+
+	// 封面
+	if o.Cover != "" {
+		m.Cover = o.Cover
+	}
+}
+
+// 提取番号并刮削，按手动指定文件修正番号、来源及元数据，
+// 所有来源均刮削失败时，若手动指定了标题及封面则直接使用手动指定数据。
+func scrapeFile(ctx context.Context, file string, cfg *util.ConfigStruct) (*Media, string, []Attempt, error) {
+	// 提取番号及版本标识
+	info := util.ParseCode(file, cfg.Code, cfg.Path.Filter)
+	code := info.Code
+
+	// 读取手动指定
+	o, err := LoadOverride(file, code)
+	// 检查
+	if err != nil {
+		return nil, code, nil, err
+	}
+
+	// 手动指定番号及来源
+	var site string
+	if o != nil {
+		if o.Code != "" {
+			code = strings.ToLower(o.Code)
+		}
+		site = o.Site
+	}
+
+	// 刮削
+	m, attempts, err := Scrape(ctx, code, cfg, site)
+	// 刮削失败时使用手动指定数据
+	if err != nil && ctx.Err() == nil && o != nil && o.complete() {
+		m, err = &Media{Number: strings.ToUpper(code), SortTitle: strings.ToUpper(code), Mpaa: "XXX", Country: "JP", Source: OverrideSource}, nil
+	}
+	// 检查
+	if err != nil {
+		return nil, code, attempts, err
+	}
+
+	// 覆盖刮削结果
+	if o != nil {
+		o.apply(m)
+	}
+	// 设置版本标识
+	m.SetVariant(info)
+
+	return m, code, attempts, nil
+}
@@ -1,82 +1,103 @@
 package media
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/ylqjgm/AVMeta/pkg/logs"
 	"os"
 	"path"
-	"regexp"
+	"path/filepath"
 	"strings"
 
 	"github.com/ylqjgm/AVMeta/pkg/util"
-
-	"github.com/ylqjgm/AVMeta/pkg/scraper"
 )
 
-// 刮削对象
-type captures struct {
-	Name string
-	S    scraper.IScraper
-	R    *regexp.Regexp
-}
-
 // Pack 整理给定影片并返回 Media 结构体，
 // 若整理失败则返回空对象及错误信息。
 //
 // file 字符串参数，传入要整理的文件路径，
 // cfg ConfigStruct结构体，传入程序配置信息。
 func Pack(file string, cfg *util.ConfigStruct) (*Media, error) {
+	return PackContext(context.Background(), file, cfg)
+}
+
+// PackContext 携带上下文整理给定影片，其余参数同 Pack，
+// 上下文被取消时将中断刮削及图片下载。
+//
+// ctx 上下文参数，传入整理所使用的上下文。
+func PackContext(ctx context.Context, file string, cfg *util.ConfigStruct) (*Media, error) {
+	return PackVideo(ctx, util.NewVideo(file), cfg)
+}
+
+// PackVideo 携带上下文整理给定视频，其余参数同 PackContext，
+// 分段视频只刮削一次，各分段共用一份元数据及图片，并分别命名为 番号-cd1、番号-cd2 等。
+//
+// v Video结构体，传入要整理的视频。
+func PackVideo(ctx context.Context, v util.Video, cfg *util.ConfigStruct) (*Media, error) {
 	if cfg.Media.Library == "vsmeta" {
-		return packVSMeta(file, cfg)
+		return packVSMeta(ctx, v, cfg)
 	}
 
-	return packNfo(file, cfg)
+	return packNfo(ctx, v, cfg)
 }
 
-// 整理给定影片为 nfo 并返回 Media 结构体，
+// 整理给定视频为 nfo 并返回 Media 结构体，
 // 若整理失败则返回空对象及错误信息。
 //
-// file 字符串参数，传入要整理的文件路径，
+// v Video结构体，传入要整理的视频，
 // cfg ConfigStruct结构体，传入程序配置信息。
-func packNfo(file string, cfg *util.ConfigStruct) (*Media, error) {
+func packNfo(ctx context.Context, v util.Video, cfg *util.ConfigStruct) (*Media, error) {
 	// 获取采集数据
-	m, err := capture(file, cfg)
+	m, err := capture(ctx, v, cfg)
 	// 检查
 	if err != nil {
 		return nil, err
 	}
 
+	// 记录外挂字幕
+	m.addSubtitles(v)
+
 	// 转换为XML
-	buff, err := mediaToXML(m)
+	buff, err := mediaToXML(m, cfg.Media.Profile)
 	// 检查
 	if err != nil {
 		return nil, err
 	}
 
 	// 写入nfo
-	err = util.WriteFile(fmt.Sprintf("%s/%s.nfo", m.DirPath, m.Number), buff)
+	nfo := fmt.Sprintf("%s/%s.nfo", m.DirPath, baseName(m, cfg, 0))
+	err = util.WriteFile(nfo, buff)
 	// 检查
 	if err != nil {
 		return nil, err
 	}
+	// 写入刮削记录
+	record := filepath.Join(m.DirPath, RecordName)
+	err = writeNfoRecord(m.DirPath, buff, nil, nil)
+	// 检查
+	if err == nil {
+		// 放置视频文件
+		err = placeVideo(m, v, cfg)
+	}
+	// 检查
+	if err != nil {
+		// 删除已生成的nfo、刮削记录及图片
+		removeGenerated(nfo, record, fmt.Sprintf("%s/fanart.jpg", m.DirPath), fmt.Sprintf("%s/poster.jpg", m.DirPath))
+		return nil, err
+	}
 
-	// 获取视频后缀
-	ext := path.Ext(file)
-	// 移动视频文件
-	err = util.MoveFile(file, fmt.Sprintf("%s/%s%s", m.DirPath, m.Number, ext))
-
-	return m, err
+	return m, nil
 }
 
-// 整理给定影片为 vsmeta 并返回 VSMeta 结构体，
+// 整理给定视频为 vsmeta 并返回 VSMeta 结构体，
 // 若整理失败则返回空对象及错误信息。
 //
-// file 字符串参数，传入要整理的文件路径，
+// v Video结构体，传入要整理的视频，
 // cfg ConfigStruct结构体，传入程序配置信息。
-func packVSMeta(file string, cfg *util.ConfigStruct) (*Media, error) {
+func packVSMeta(ctx context.Context, v util.Video, cfg *util.ConfigStruct) (*Media, error) {
 	// 获取整理数据
-	m, err := capture(file, cfg)
+	m, err := capture(ctx, v, cfg)
 	// 检查
 	if err != nil {
 		return nil, err
@@ -84,46 +105,124 @@ func packVSMeta(file string, cfg *util.ConfigStruct) (*Media, error) {
 
 	// 实例化VSMeta
 	vs := NewVSMeta()
+	vs.Image = cfg.Image
+	vs.Proxy = cfg.Base.Proxy
 	// 解析为 vsmeta
 	vs.ParseVSMeta(m)
 	// 写入封面
-	vs.writePoster(fmt.Sprintf("%s/poster.jpg", m.DirPath))
+	vs.writePoster(ctx, fmt.Sprintf("%s/poster.jpg", m.DirPath))
 	// 写入背景
-	vs.writeFanart(fmt.Sprintf("%s/fanart.jpg", m.DirPath))
-
-	// 获取视频后缀
-	ext := path.Ext(file)
-
-	// 写入vsmeta
-	err = util.WriteFile(fmt.Sprintf("%s/%s%s.vsmeta", m.DirPath, m.Number, ext), vs.B.Bytes())
+	vs.writeFanart(ctx, fmt.Sprintf("%s/fanart.jpg", m.DirPath))
+
+	// 每个分段写入一份vsmeta
+	var files []string
+	for _, part := range v.Parts {
+		// 写入vsmeta
+		file := VideoPath(m, cfg, part) + ".vsmeta"
+		err = util.WriteFile(file, vs.B.Bytes())
+		// 检查
+		if err != nil {
+			removeGenerated(files...)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	// 写入刮削记录
+	err = writeVSMetaRecord(m.DirPath, vs.B.Bytes(), nil, nil)
 	// 检查
 	if err != nil {
+		removeGenerated(files...)
 		return nil, err
 	}
+	files = append(files, filepath.Join(m.DirPath, RecordName))
 
 	// 删除封面
 	_ = os.Remove(fmt.Sprintf("%s/poster.jpg", m.DirPath))
 	// 删除背景
 	_ = os.Remove(fmt.Sprintf("%s/fanart.jpg", m.DirPath))
 
-	// 移动视频文件
-	err = util.MoveFile(file, fmt.Sprintf("%s/%s%s", m.DirPath, m.Number, ext))
+	// 放置视频文件
+	err = placeVideo(m, v, cfg)
+	// 检查
+	if err != nil {
+		// 删除已生成的vsmeta
+		removeGenerated(files...)
+		return nil, err
+	}
 
-	return m, err
+	return m, nil
+}
+
+// 按整理方式放置视频的所有分段文件及其字幕等附属文件，
+// 附属文件与视频使用相同的名称，并保留语言标识，
+// 任一文件放置失败时撤销已放置的文件，避免影片只整理了一部分。
+func placeVideo(m *Media, v util.Video, cfg *util.ConfigStruct) error {
+	// 已放置的文件，原始路径及放置路径
+	var placed [][2]string
+	// 放置文件
+	place := func(src, dst string) error {
+		if err := util.PlaceFile(src, dst, cfg.Path.Mode); err != nil {
+			return err
+		}
+		placed = append(placed, [2]string{src, dst})
+		return nil
+	}
+
+	for _, part := range v.Parts {
+		// 查找附属文件
+		sidecars := util.FindSidecars(part.File)
+
+		// 放置视频
+		if err := place(part.File, VideoPath(m, cfg, part)); err != nil {
+			unplace(placed, cfg.Path.Mode)
+			return err
+		}
+
+		// 放置附属文件
+		for _, sc := range sidecars {
+			// 附属文件路径
+			target := fmt.Sprintf("%s/%s%s", m.DirPath, baseName(m, cfg, part.Index), sc.Suffix)
+			if err := place(sc.File, target); err != nil {
+				unplace(placed, cfg.Path.Mode)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 按相反顺序撤销已放置的文件
+func unplace(placed [][2]string, mode string) {
+	for i := len(placed) - 1; i >= 0; i-- {
+		if err := util.UnplaceFile(placed[i][0], placed[i][1], mode); err != nil {
+			logs.Warning("文件 [%s] 撤销放置失败, 错误原因: %s", path.Base(placed[i][1]), err)
+		}
+	}
+}
+
+// 删除整理过程中生成的元数据及图片文件
+func removeGenerated(files ...string) {
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
 }
 
 // 整理影片并返回 Media 对象
 //
-// file 字符串参数，传入要整理的文件路径，
+// v Video结构体，传入要整理的视频，
 // cfg ConfigStruct结构体，传入程序配置信息。
-func capture(file string, cfg *util.ConfigStruct) (*Media, error) {
+func capture(ctx context.Context, v util.Video, cfg *util.ConfigStruct) (*Media, error) {
 	// 搜索番号并获得刮削对象
-	m, err := search(file, cfg)
+	m, err := search(ctx, v.Name, cfg)
 	// 检查
 	if err != nil {
 		return nil, err
 	}
 
+	// 读取视频信息，需在获取目录前执行
+	m.addStreams(v)
+
 	// 是否有图片
 	if m.Cover == "" {
 		return nil, fmt.Errorf("找不到封面")
@@ -132,7 +231,7 @@ func capture(file string, cfg *util.ConfigStruct) (*Media, error) {
 	// 获取准确目录
 	dirPath := util.GetNumberPath(m.ConvertMap(), cfg)
 	// 创建目录
-	err = os.MkdirAll(dirPath, os.ModePerm)
+	err = util.MkdirAll(dirPath)
 	// 检查
 	if err != nil {
 		return nil, err
@@ -143,7 +242,7 @@ func capture(file string, cfg *util.ConfigStruct) (*Media, error) {
 	// 获取图片后缀
 	ext := path.Ext(m.Cover)
 	// 下载图片
-	err = util.SavePhoto(m.Cover, fmt.Sprintf("%s/fanart.jpg", m.DirPath), cfg.Base.Proxy, !strings.EqualFold(strings.ToLower(ext), ".jpg"))
+	err = util.SavePhotoContext(ctx, m.Cover, fmt.Sprintf("%s/fanart.jpg", m.DirPath), cfg.Base.Proxy, !strings.EqualFold(strings.ToLower(ext), ".jpg"))
 	// 检查
 	if err != nil {
 		return nil, err
@@ -165,127 +264,70 @@ func capture(file string, cfg *util.ConfigStruct) (*Media, error) {
 }
 
 // 番号搜索
-func search(file string, cfg *util.ConfigStruct) (*Media, error) {
-	// 定义变量
-	var err error
-
-	// 提取番号
-	code := util.GetCode(file, cfg.Code, cfg.Path.Filter)
-	fmt.Printf("code is %s\n", code)
-
-	// 定义一个拥有正则匹配的刮削对象数组
-	sr := []captures{
-		{
-			Name: "CaribBeanCom",
-			S:    scraper.NewCaribBeanComScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`^\d{6}-\d{3}$`),
-		},
-		{
-			Name: "TokyoHot",
-			S:    scraper.NewTokyoHotScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`(^red-\d{3}|n\d{4})`),
-		},
-		{
-			Name: "Heyzo",
-			S:    scraper.NewHeyzoScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`^heyzo-[0-9]{4}`),
-		},
-		{
-			Name: "Heydouga",
-			S:    scraper.NewHeydougaScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`([0-9]{4}).+?([0-9]{3,4})$`),
-		},
-		{
-			Name: "FC2",
-			S:    scraper.NewFC2Scraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`^fc2-(ppv-)?[0-9]{6,7}`),
-		},
-		{
-			Name: "Siro",
-			S:    scraper.NewSiroScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`^(siro|abp|[0-9]{3,4}[a-zA-Z]{2,5})-[0-9]{3,4}`),
-		},
-		{
-			Name: "DMM",
-			S:    scraper.NewDMMScraper(cfg.Base.Proxy),
-			R:    regexp.MustCompile(`[a-zA-Z]{2,5}[-|\s\S][0-9]{3,4}`),
-		},
-	}
-	// 定义一个没有正则匹配的刮削对象数组
-	ss := []captures{
-		{
-			Name: "JavDB",
-			S:    scraper.NewJavDBScraper(cfg.Site.JavDB, cfg.Base.Proxy),
-			R:    nil,
-		},
-		{
-			Name: "JavBus",
-			S:    scraper.NewJavBusScraper(cfg.Site.JavBus, cfg.Base.Proxy),
-			R:    nil,
-		},
-	}
-
-	// 转换番号为小写
-	code = strings.ToLower(code)
-	// 定义一个刮削对象
-	var s scraper.IScraper
-
-	// 计数变量
-	i := 0
-	// 刮削网站变量
-	var site string
-
-	// 查找正则匹配
-	for _, scr := range sr {
-		// 检查是否匹配
-		if scr.R.MatchString(code) {
-			// 计数自增
-			i++
-			// 刮削赋值
-			s = scr.S
-			// 刮削网站
-			site = scr.Name
-			// 刮削
-			if err = s.Fetch(code); err == nil {
-				break
-			} else {
-				logs.Info("文件 [%s -> %s] 第 %d 次刮削失败，刮削来源：[%s]，错误原因：%s", path.Base(file), code, i, scr.Name, err)
-			}
+func search(ctx context.Context, file string, cfg *util.ConfigStruct) (*Media, error) {
+	// 提取番号并刮削
+	m, code, attempts, err := scrapeFile(ctx, file, cfg)
+
+	// 输出失败来源
+	for i, a := range attempts {
+		if a.Err != nil {
+			logs.Info("文件 [%s -> %s] 第 %d 次刮削失败，刮削来源：[%s]，错误原因：%s", path.Base(file), strings.ToLower(code), i+1, a.Site, a.Err)
 		}
 	}
 
-	// 检查错误
-	if err != nil || s == nil {
-		// 尝试刮削
-		for _, sc := range ss {
-			// 计数自增
-			i++
-			// 刮削赋值
-			s = sc.S
-			// 刮削网站
-			site = sc.Name
-			// 刮削
-			if err = s.Fetch(code); err == nil {
-				break
-			} else {
-				logs.Info("文件 [%s -> %s] 第 %d 次刮削失败，刮削来源：[%s]，错误原因：%s", path.Base(file), code, i, sc.Name, err)
-			}
-		}
+	return m, err
+}
+
+// NFO 将 Media 按指定格式转换为 nfo 文件内容
+//
+// profile 字符串参数，传入 nfo 格式，可选 emby、kodi、jellyfin，为空时为 emby。
+func (m *Media) NFO(profile string) ([]byte, error) {
+	return mediaToXML(m, profile)
+}
+
+// VideoPath 获取视频分段文件整理后的路径
+//
+// m Media结构体，传入整理后的影片信息，
+// cfg ConfigStruct结构体，传入程序配置信息，
+// part VideoPart结构体，传入视频分段。
+func VideoPath(m *Media, cfg *util.ConfigStruct, part util.VideoPart) string {
+	return fmt.Sprintf("%s/%s%s", m.DirPath, baseName(m, cfg, part.Index), path.Ext(part.File))
+}
+
+// 按文件名称规则获取整理后的文件名称，不含扩展名，
+// 规则中没有 {part} 时分段标识将加在名称末尾，避免分段文件重名。
+func baseName(m *Media, cfg *util.ConfigStruct, index int) string {
+	// 替换内容
+	replaceMap := m.ConvertMap()
+	replaceMap["{part}"] = util.PartSuffix(index)
+
+	// 文件名称规则
+	rule := cfg.Path.Filename
+	if strings.TrimSpace(rule) == "" {
+		rule = util.DefaultFilename
 	}
 
-	// 再次检测
-	if err != nil || s == nil {
-		return nil, err
+	// 获取名称
+	name := util.GetFileName(replaceMap, cfg)
+	// 是否需要补充分段标识
+	if index > 0 && !strings.Contains(rule, "{part}") {
+		name += util.PartSuffix(index)
 	}
 
-	// 刮削并获取nfo对象
-	return ParseMedia(s, site)
+	return name
 }
 
-// 转换为xml
-func mediaToXML(m *Media) ([]byte, error) {
+// 按格式转换为xml
+func mediaToXML(m *Media, profile string) ([]byte, error) {
+	// 转换为nfo结构
+	n, err := newNfoMovie(m, profile)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
 	// 转换
-	x, err := xml.MarshalIndent(m, "", "  ")
+	x, err := xml.MarshalIndent(n, "", "  ")
 	// 检查
 	if err != nil {
 		return nil, err
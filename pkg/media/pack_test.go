@@ -0,0 +1,60 @@
+package media
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+func TestPlaceVideoRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "place")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 两个分段及字幕
+	input := filepath.Join(dir, "input")
+	if err = os.MkdirAll(input, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	var files []string
+	for _, name := range []string{"SSIS-001-cd1.mp4", "SSIS-001-cd1.srt", "SSIS-001-cd2.mp4"} {
+		file := filepath.Join(input, name)
+		if err = ioutil.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+	videos := util.GroupVideos([]string{files[0], files[2]})
+	if len(videos) != 1 || len(videos[0].Parts) != 2 {
+		t.Fatalf("GroupVideos() = %v", videos)
+	}
+
+	// 第二个分段的目标位置被非空目录占用
+	m := &Media{Number: "SSIS-001", DirPath: filepath.Join(dir, "success")}
+	cfg := &util.ConfigStruct{}
+	cfg.Path.Mode = util.ModeMove
+	blocked := VideoPath(m, cfg, videos[0].Parts[1])
+	if err = os.MkdirAll(filepath.Join(blocked, "keep"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	// 放置失败
+	if err = placeVideo(m, videos[0], cfg); err == nil {
+		t.Fatal("placeVideo() 未返回错误")
+	}
+
+	// 已放置的分段及字幕被移回原位
+	for _, file := range files {
+		if !util.Exists(file) {
+			t.Errorf("%s 未回到原位", filepath.Base(file))
+		}
+	}
+	if placed := VideoPath(m, cfg, videos[0].Parts[0]); util.Exists(placed) {
+		t.Errorf("%s 未被撤销", filepath.Base(placed))
+	}
+}
@@ -0,0 +1,53 @@
+package media
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// PlanItem 整理计划条目，记录影片整理后的目标位置及刮削情况。
+type PlanItem struct {
+	File     string    // 影片原始路径
+	Code     string    // 提取到的番号
+	Dir      string    // 整理后的目录
+	Target   string    // 整理后的影片路径
+	Source   string    // 刮削来源
+	Attempts []Attempt // 刮削尝试记录
+	Err      error     // 失败原因
+}
+
+// Plan 刮削给定视频并返回各分段文件的整理计划，
+// 该方法仅提取番号及刮削数据，不会创建目录、下载图片、移动文件或写入缓存。
+//
+// ctx 上下文参数，传入刮削所使用的上下文，
+// v Video结构体，传入要整理的视频，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func Plan(ctx context.Context, v util.Video, cfg *util.ConfigStruct) []*PlanItem {
+	// 提取番号并刮削，只读取已有缓存
+	m, code, attempts, err := scrapeFile(util.WithReadOnlyCache(ctx), v.Name, cfg)
+	// 是否有图片，与实际整理时保持一致
+	if err == nil && m.Cover == "" {
+		err = fmt.Errorf("找不到封面")
+	}
+	// 读取视频信息并获取准确目录
+	if err == nil {
+		m.addStreams(v)
+		m.DirPath = util.GetNumberPath(m.ConvertMap(), cfg)
+	}
+
+	// 计划条目
+	items := make([]*PlanItem, 0, len(v.Parts))
+	for _, part := range v.Parts {
+		item := &PlanItem{File: part.File, Code: code, Attempts: attempts, Err: err}
+		if err == nil {
+			item.Source = m.Source
+			item.Dir = m.DirPath
+			item.Target = VideoPath(m, cfg, part)
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// protobuf 字段编码类型
+const (
+	wireVarint  = 0 // 变长整数
+	wireFixed64 = 1 // 64 位定长
+	wireBytes   = 2 // 长度前缀的字节集，包括字符串及嵌套消息
+	wireFixed32 = 5 // 32 位定长
+)
+
+// protobuf 字段
+type protoField struct {
+	num   int    // 字段编号
+	wire  int    // 编码类型
+	value uint64 // 整数值，编码类型为变长整数或定长时有效
+	data  []byte // 字节集，编码类型为字节集时有效
+}
+
+// 解析 protobuf 消息中的所有字段，按出现顺序返回
+func decodeProto(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for off := 0; off < len(b); {
+		// 字段键，编号及编码类型
+		key, n := binary.Uvarint(b[off:])
+		if n <= 0 {
+			return fields, fmt.Errorf("位置 %d: 字段键错误", off)
+		}
+		f := protoField{num: int(key >> 3), wire: int(key & 0x07)}
+		if f.num == 0 {
+			return fields, fmt.Errorf("位置 %d: 字段编号错误", off)
+		}
+		off += n
+
+		// 字段内容
+		switch f.wire {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b[off:])
+			if n <= 0 {
+				return fields, fmt.Errorf("位置 %d: 字段 %d 数值错误", off, f.num)
+			}
+			off += n
+		case wireFixed64:
+			if off+8 > len(b) {
+				return fields, fmt.Errorf("位置 %d: 字段 %d 长度不足", off, f.num)
+			}
+			f.value = binary.LittleEndian.Uint64(b[off:])
+			off += 8
+		case wireBytes:
+			length, n := binary.Uvarint(b[off:])
+			if n <= 0 || length > uint64(len(b)-off-n) {
+				return fields, fmt.Errorf("位置 %d: 字段 %d 长度错误", off, f.num)
+			}
+			off += n
+			f.data = b[off : off+int(length)]
+			off += int(length)
+		case wireFixed32:
+			if off+4 > len(b) {
+				return fields, fmt.Errorf("位置 %d: 字段 %d 长度不足", off, f.num)
+			}
+			f.value = uint64(binary.LittleEndian.Uint32(b[off:]))
+			off += 4
+		default:
+			return fields, fmt.Errorf("位置 %d: 字段 %d 编码类型 %d 不支持", off, f.num, f.wire)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// protobuf 消息编码器，按调用顺序写入字段
+type protoEncoder struct {
+	buf *bytes.Buffer
+}
+
+// 写入无符号变长整数
+func (e *protoEncoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	e.buf.Write(b[:n])
+}
+
+// 写入字段键
+func (e *protoEncoder) key(num, wire int) {
+	e.uvarint(uint64(num)<<3 | uint64(wire))
+}
+
+// 写入变长整数字段
+func (e *protoEncoder) writeVarint(num int, v uint64) {
+	e.key(num, wireVarint)
+	e.uvarint(v)
+}
+
+// 写入字节集字段
+func (e *protoEncoder) writeBytes(num int, b []byte) {
+	e.key(num, wireBytes)
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+// 写入字符串字段
+func (e *protoEncoder) writeString(num int, s string) {
+	e.key(num, wireBytes)
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// 写入嵌套消息字段，fn 中写入消息内容，消息为空时不写入
+func (e *protoEncoder) writeMessage(num int, fn func(e *protoEncoder)) {
+	var msg bytes.Buffer
+	fn(&protoEncoder{buf: &msg})
+	if msg.Len() > 0 {
+		e.writeBytes(num, msg.Bytes())
+	}
+}
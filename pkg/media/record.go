@@ -0,0 +1,135 @@
+package media
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// RecordName 刮削记录文件名称，保存在影片目录中
+const RecordName = ".avmeta.json"
+
+// 刮削记录，保存最近一次写入元数据时各字段的摘要，
+// 刷新时当前值与摘要不一致的字段视为手动修改过，不会被覆盖。
+type scrapeRecord struct {
+	Fields map[string]string `json:"fields"` // 字段名称及摘要
+}
+
+// 读取目录中的刮削记录，不存在或无法解析时返回 nil
+//
+// dir 字符串参数，传入影片目录。
+func readRecord(dir string) *scrapeRecord {
+	// 读取文件
+	b, err := util.ReadFile(filepath.Join(dir, RecordName))
+	// 检查
+	if err != nil {
+		return nil
+	}
+
+	// 解析
+	var r scrapeRecord
+	if err = json.Unmarshal(b, &r); err != nil || r.Fields == nil {
+		return nil
+	}
+
+	return &r
+}
+
+// 检查字段是否被手动修改过，没有记录时视为未修改
+//
+// m Media结构体，传入当前数据，
+// name 字符串参数，传入字段名称。
+func (r *scrapeRecord) edited(m *Media, name string) bool {
+	// 没有记录
+	if r == nil || r.Fields[name] == "" {
+		return false
+	}
+
+	for _, f := range refreshFields {
+		if f.name == name {
+			return r.Fields[name] != fieldHash(f.value(m))
+		}
+	}
+
+	return false
+}
+
+// 按写入后读取到的数据生成刮削记录并写入目录，
+// 被锁定或因手动修改而保留的字段沿用原有摘要，使其在之后的刷新中仍被保留。
+//
+// dir 字符串参数，传入影片目录，
+// m Media结构体，传入从写入的文件中读取的数据，
+// old scrapeRecord结构体，传入原有记录，可为 nil，
+// kept 字符串列表参数，传入因手动修改而保留的字段。
+func writeRecord(dir string, m *Media, old *scrapeRecord, kept []string) error {
+	r := scrapeRecord{Fields: make(map[string]string, len(refreshFields))}
+	for _, f := range refreshFields {
+		if old != nil && old.Fields[f.name] != "" && (containsField(kept, f.name) || m.locked(f.name)) {
+			r.Fields[f.name] = old.Fields[f.name]
+			continue
+		}
+		r.Fields[f.name] = fieldHash(f.value(m))
+	}
+
+	// 转换
+	b, err := json.MarshalIndent(r, "", "  ")
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	return util.WriteFile(filepath.Join(dir, RecordName), b)
+}
+
+// 按写入的 nfo 内容生成刮削记录，参数同 writeRecord
+//
+// b 字节集参数，传入写入的 nfo 内容。
+func writeNfoRecord(dir string, b []byte, old *scrapeRecord, kept []string) error {
+	// 读取写入的数据
+	m, err := decodeNfo(b)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	return writeRecord(dir, m, old, kept)
+}
+
+// 按写入的 vsmeta 内容生成刮削记录，参数同 writeRecord
+//
+// b 字节集参数，传入写入的 vsmeta 内容。
+func writeVSMetaRecord(dir string, b []byte, old *scrapeRecord, kept []string) error {
+	// 读取写入的数据
+	v, err := DecodeVSMeta(b)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	return writeRecord(dir, v.Media, old, kept)
+}
+
+// 计算字段值摘要
+func fieldHash(v interface{}) string {
+	b, _ := json.Marshal(v)
+	sum := sha1.Sum(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// 演员列表按名称排序，比较时忽略顺序
+func sortedActors(actors []Actor) []Actor {
+	sorted := append([]Actor(nil), actors...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Thumb < sorted[j].Thumb
+	})
+
+	return sorted
+}
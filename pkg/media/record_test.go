@@ -0,0 +1,84 @@
+package media
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMergeRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 整理时写入 nfo 及刮削记录
+	b, err := mediaToXML(nfoMedia(), ProfileEmby)
+	if err != nil {
+		t.Fatalf("mediaToXML() error = %v", err)
+	}
+	if err = writeNfoRecord(dir, b, nil, nil); err != nil {
+		t.Fatalf("writeNfoRecord() error = %v", err)
+	}
+	record := readRecord(dir)
+	if record == nil {
+		t.Fatal("readRecord() = nil")
+	}
+
+	// 在媒体库中手动修改标题及演员
+	m, err := decodeNfo(b)
+	if err != nil {
+		t.Fatalf("decodeNfo() error = %v", err)
+	}
+	m.LockedFields = ""
+	m.Title.Inner = "手动修改的标题"
+	m.Actor = []Actor{{Name: "河北彩花"}}
+
+	// 重新刮削的数据
+	n := nfoMedia()
+	n.Title.Inner = "新标题"
+	n.Actor = []Actor{{Name: "新演员"}}
+	n.Plot = Inner{Inner: "新简介"}
+	n.Outline = n.Plot
+	n.Rating, n.Votes = 9.04, 200
+
+	changed, kept := m.merge(n, record)
+	if want := []string{"plot", "rating"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"title", "actor"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if m.Title.Inner != "手动修改的标题" || len(m.Actor) != 1 || m.Actor[0].Name != "河北彩花" {
+		t.Errorf("手动修改被覆盖, title = %q, actor = %v", m.Title.Inner, m.Actor)
+	}
+	if m.Plot.Inner != "新简介" || m.Rating != 9 || m.Votes != 200 {
+		t.Errorf("未更新, plot = %q, rating = %v, votes = %d", m.Plot.Inner, m.Rating, m.Votes)
+	}
+
+	// 刷新后写入新记录，保留的字段在下次刷新时仍视为手动修改
+	if b, err = mediaToXML(m, ProfileEmby); err != nil {
+		t.Fatalf("mediaToXML() error = %v", err)
+	}
+	if err = writeNfoRecord(dir, b, record, kept); err != nil {
+		t.Fatalf("writeNfoRecord() error = %v", err)
+	}
+	record = readRecord(dir)
+	if m, err = decodeNfo(b); err != nil {
+		t.Fatalf("decodeNfo() error = %v", err)
+	}
+	for _, f := range refreshFields {
+		want := f.name == "title" || f.name == "actor"
+		if got := record.edited(m, f.name); got != want {
+			t.Errorf("edited(%s) = %v, want %v", f.name, got, want)
+		}
+	}
+
+	// 没有刮削记录时不识别手动修改
+	m.Title.Inner = "手动修改的标题"
+	if changed, kept = m.merge(n, nil); !containsField(changed, "title") || len(kept) != 0 {
+		t.Errorf("merge() = %v, %v, 没有记录时应更新标题", changed, kept)
+	}
+}
@@ -0,0 +1,604 @@
+package media
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// ErrLocked 元数据已被媒体库锁定
+var ErrLocked = errors.New("元数据已锁定")
+
+// RefreshOptions 刷新元数据选项
+type RefreshOptions struct {
+	Site    string // 指定刮削来源，为空则按番号自动选择
+	Artwork bool   // 是否重新下载图片
+	DryRun  bool   // 仅比较数据，不写入任何文件
+}
+
+// RefreshResult 刷新元数据结果
+type RefreshResult struct {
+	Media    *Media    // 刷新后的元数据
+	Changed  []string  // 已更新的字段
+	Kept     []string  // 手动修改过而保留的字段
+	Attempts []Attempt // 刮削尝试记录
+}
+
+// String 输出刷新结果概要
+func (r *RefreshResult) String() string {
+	// 概要内容
+	var parts []string
+	if len(r.Changed) == 0 {
+		parts = append(parts, "无变化")
+	} else {
+		parts = append(parts, fmt.Sprintf("已更新 %s", strings.Join(r.Changed, ", ")))
+	}
+	// 保留的手动修改
+	if len(r.Kept) > 0 {
+		parts = append(parts, fmt.Sprintf("保留手动修改 %s", strings.Join(r.Kept, ", ")))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// 可刷新的字段
+var refreshFields = []struct {
+	name    string                     // 字段名称
+	aliases []string                   // 媒体库中的字段名称，用于匹配 lockedfields
+	value   func(m *Media) interface{} // 字段当前值，用于识别手动修改
+	merge   func(old, new *Media) bool // 合并字段，返回是否有变化
+}{
+	{"title", []string{"name"}, func(m *Media) interface{} {
+		return m.Title.Inner
+	}, func(old, new *Media) bool {
+		return mergeString(&old.Title.Inner, new.Title.Inner)
+	}},
+	{"plot", []string{"overview"}, func(m *Media) interface{} {
+		return []string{m.Plot.Inner, m.Outline.Inner}
+	}, func(old, new *Media) bool {
+		changed := mergeString(&old.Plot.Inner, new.Plot.Inner)
+		return mergeString(&old.Outline.Inner, new.Outline.Inner) || changed
+	}},
+	{"studio", []string{"studios"}, func(m *Media) interface{} {
+		return []string{m.Studio.Inner, m.Maker.Inner}
+	}, func(old, new *Media) bool {
+		changed := mergeString(&old.Studio.Inner, new.Studio.Inner)
+		return mergeString(&old.Maker.Inner, new.Maker.Inner) || changed
+	}},
+	{"director", nil, func(m *Media) interface{} {
+		return m.Director.Inner
+	}, func(old, new *Media) bool {
+		return mergeString(&old.Director.Inner, new.Director.Inner)
+	}},
+	{"release", []string{"premiered", "premieredate", "productionyear"}, func(m *Media) interface{} {
+		return []string{m.Release, m.Premiered, m.Year, m.Month}
+	}, func(old, new *Media) bool {
+		changed := mergeString(&old.Release, new.Release)
+		changed = mergeString(&old.Premiered, new.Premiered) || changed
+		changed = mergeString(&old.Year, new.Year) || changed
+		return mergeString(&old.Month, new.Month) || changed
+	}},
+	{"runtime", nil, func(m *Media) interface{} {
+		return m.RunTime
+	}, func(old, new *Media) bool {
+		return mergeString(&old.RunTime, new.RunTime)
+	}},
+	{"actor", []string{"actors", "cast"}, func(m *Media) interface{} {
+		return sortedActors(m.Actor)
+	}, func(old, new *Media) bool {
+		// 刮削来源没有角色信息，保留原有的角色
+		actors := keepRoles(new.Actor, old.Actor)
+		if len(actors) == 0 || sameActors(old.Actor, actors) {
+			return false
+		}
+		old.Actor = actors
+		return true
+	}},
+	{"tag", []string{"tags"}, func(m *Media) interface{} {
+		return m.Tag
+	}, func(old, new *Media) bool {
+		return mergeInners(&old.Tag, variantTags(new.Tag, old.Tag))
+	}},
+	{"genre", []string{"genres"}, func(m *Media) interface{} {
+		return m.Genre
+	}, func(old, new *Media) bool {
+		return mergeInners(&old.Genre, variantTags(new.Genre, old.Genre))
+	}},
+	{"writer", []string{"credits"}, func(m *Media) interface{} {
+		return m.Writer
+	}, func(old, new *Media) bool {
+		return mergeString(&old.Writer, new.Writer)
+	}},
+	{"set", []string{"series"}, func(m *Media) interface{} {
+		return m.Set
+	}, func(old, new *Media) bool {
+		return mergeString(&old.Set, new.Set)
+	}},
+	{"cover", []string{"images"}, func(m *Media) interface{} {
+		return m.Cover
+	}, func(old, new *Media) bool {
+		return mergeString(&old.Cover, new.Cover)
+	}},
+	{"website", nil, func(m *Media) interface{} {
+		return m.WebSite
+	}, func(old, new *Media) bool {
+		return mergeString(&old.WebSite, new.WebSite)
+	}},
+	{"rating", []string{"ratings", "communityrating"}, func(m *Media) interface{} {
+		return []interface{}{roundRating(m.Rating), m.Votes}
+	}, func(old, new *Media) bool {
+		// nfo 中评分保留一位小数
+		if new.Rating <= 0 || (roundRating(old.Rating) == roundRating(new.Rating) && old.Votes == new.Votes) {
+			return false
+		}
+		old.Rating, old.Votes = roundRating(new.Rating), new.Votes
+		return true
+	}},
+}
+
+// ReadNfo 读取 nfo 文件并返回 Media 结构体，兼容 emby、kodi、jellyfin 格式
+//
+// file 字符串参数，传入 nfo 文件路径。
+func ReadNfo(file string) (*Media, error) {
+	// 读取文件
+	b, err := util.ReadFile(file)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeNfo(b)
+}
+
+// 解析 nfo 内容并返回 Media 结构体
+func decodeNfo(b []byte) (*Media, error) {
+	// 解析
+	var n nfoMovie
+	err := xml.Unmarshal(b, &n)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	return n.media(), nil
+}
+
+// Refresh 重新刮削已整理影片的元数据，并在原位置更新 nfo、vsmeta 及图片，不会移动视频文件。
+// nfo 中 lockdata 为 true 的影片将被跳过，lockedfields 中列出的字段保持不变；
+// 整理及刷新时会在影片目录中写入刮削记录，当前值与记录不一致的字段视为手动修改过，同样保持不变，
+// 没有刮削记录的影片（旧版本整理）无法识别手动修改，首次刷新时写入记录；
+// 没有 nfo 的目录读取 vsmeta 文件，vsmeta 不保存锁定字段，只保留手动修改过的字段。
+//
+// ctx 上下文参数，传入刮削所使用的上下文，
+// nfo NfoFile结构体，传入要刷新的 nfo 或 vsmeta 文件，
+// cfg ConfigStruct结构体，传入程序配置信息，
+// opts RefreshOptions结构体，传入刷新选项。
+func Refresh(ctx context.Context, nfo util.NfoFile, cfg *util.ConfigStruct, opts RefreshOptions) (*RefreshResult, error) {
+	// 仅比较数据时不写入缓存
+	if opts.DryRun {
+		ctx = util.WithReadOnlyCache(ctx)
+	}
+
+	// vsmeta 元数据
+	if strings.EqualFold(filepath.Ext(nfo.Path), ".vsmeta") {
+		return refreshVSMetaOnly(ctx, nfo, cfg, opts)
+	}
+
+	// 读取原有数据
+	m, err := ReadNfo(nfo.Path)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	result := &RefreshResult{Media: m}
+	// 刮削记录
+	record := readRecord(nfo.Dir)
+
+	// 已锁定
+	if m.LockData {
+		return result, ErrLocked
+	}
+
+	// 重新刮削
+	n, attempts, err := rescrape(ctx, m, nfo.Path, cfg, opts.Site)
+	result.Attempts = attempts
+	// 检查
+	if err != nil {
+		return result, err
+	}
+	// 非 Emby 格式不保存网址，避免每次刷新都视为有变化
+	if cfg.Media.Profile != "" && cfg.Media.Profile != ProfileEmby {
+		n.WebSite = ""
+	}
+
+	// 合并数据
+	result.Changed, result.Kept = m.merge(n, record)
+
+	// 是否需要下载图片
+	fanart := nfo.Dir + "/fanart.jpg"
+	poster := nfo.Dir + "/poster.jpg"
+	if !m.locked("cover") && m.Cover != "" &&
+		(opts.Artwork || containsField(result.Changed, "cover") || !util.Exists(fanart) || !util.Exists(poster)) {
+		result.Changed = append(result.Changed, "artwork")
+	}
+
+	// 仅比较数据
+	if opts.DryRun {
+		return result, nil
+	}
+	// 没有变化，补写旧版本整理的影片缺少的刮削记录
+	if len(result.Changed) == 0 {
+		if record == nil {
+			return result, writeRecord(nfo.Dir, m, nil, nil)
+		}
+		return result, nil
+	}
+
+	// 下载图片
+	if containsField(result.Changed, "artwork") {
+		err = util.SavePhotoContext(ctx, m.Cover, fanart, cfg.Base.Proxy, !strings.EqualFold(path.Ext(m.Cover), ".jpg"))
+		// 检查
+		if err != nil {
+			return result, err
+		}
+		// 裁剪封面
+		err = util.PosterCover(fanart, poster, cfg)
+		// 检查
+		if err != nil {
+			return result, err
+		}
+		m.FanArt = "fanart.jpg"
+		m.Poster = "poster.jpg"
+		m.Thumb = "poster.jpg"
+	}
+
+	// 写入nfo
+	buff, err := mediaToXML(m, cfg.Media.Profile)
+	// 检查
+	if err != nil {
+		return result, err
+	}
+	err = util.WriteFile(nfo.Path, buff)
+	// 检查
+	if err != nil {
+		return result, err
+	}
+	// 按写入的内容更新刮削记录
+	err = writeNfoRecord(nfo.Dir, buff, record, result.Kept)
+	// 检查
+	if err != nil {
+		return result, err
+	}
+
+	return result, refreshVSMeta(ctx, m, nfo.Dir, cfg)
+}
+
+// 按原有数据中的番号重新刮削，番号为空时从文件名称提取
+func rescrape(ctx context.Context, m *Media, file string, cfg *util.ConfigStruct, site string) (*Media, []Attempt, error) {
+	// 番号
+	code := m.Number
+	if code == "" {
+		code = util.ParseCode(file, cfg.Code, cfg.Path.Filter).Code
+	}
+
+	// 重新刮削
+	n, attempts, err := Scrape(ctx, code, cfg, site)
+	// 检查
+	if err != nil {
+		return nil, attempts, err
+	}
+	m.Source = n.Source
+
+	return n, attempts, nil
+}
+
+// 刷新没有 nfo 的目录中的 vsmeta 文件，封面及背景内嵌在 vsmeta 中，
+// 未重新下载图片时保留原有的内嵌图片。
+func refreshVSMetaOnly(ctx context.Context, nfo util.NfoFile, cfg *util.ConfigStruct, opts RefreshOptions) (*RefreshResult, error) {
+	// 读取原有数据
+	v, err := ReadVSMeta(nfo.Path)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	m := v.Media
+	result := &RefreshResult{Media: m}
+	// 刮削记录
+	record := readRecord(nfo.Dir)
+
+	// 重新刮削
+	n, attempts, err := rescrape(ctx, m, nfo.Path, cfg, opts.Site)
+	result.Attempts = attempts
+	// 检查
+	if err != nil {
+		return result, err
+	}
+
+	// vsmeta 中不保存的数据不参与比较
+	cover := n.Cover
+	n.Outline, n.Maker, n.Tag = Inner{}, Inner{}, nil
+	n.Release, n.Month, n.RunTime, n.Set, n.Cover, n.WebSite = "", "", "", "", "", ""
+	n.Rating, n.Votes, n.Writer = 0, 0, ""
+	actors := make([]Actor, 0, len(n.Actor))
+	for _, a := range n.Actor {
+		actors = append(actors, Actor{Name: a.Name})
+	}
+	n.Actor = actors
+	// vsmeta 中的标题及简介可能被截取
+	n.Title.Inner = vsmetaSubStr(n.Title.Inner, vsmetaTitleMax, vsmetaTitleLen)
+	n.Plot.Inner = vsmetaSubStr(n.Plot.Inner, vsmetaSummaryMax, vsmetaSummaryLen)
+
+	// 合并数据
+	result.Changed, result.Kept = m.merge(n, record)
+
+	// 是否需要下载图片
+	if cover != "" && (opts.Artwork || len(v.Poster) == 0 || len(v.Backdrop) == 0) {
+		result.Changed = append(result.Changed, "artwork")
+	}
+
+	// 仅比较数据
+	if opts.DryRun {
+		return result, nil
+	}
+	// 没有变化，补写旧版本整理的影片缺少的刮削记录
+	if len(result.Changed) == 0 {
+		if record == nil {
+			return result, writeRecord(nfo.Dir, m, nil, nil)
+		}
+		return result, nil
+	}
+
+	// 转换
+	var bs []byte
+	if containsField(result.Changed, "artwork") {
+		// 下载图片到临时文件，目录中已有的图片不删除
+		fanart := nfo.Dir + "/fanart.jpg"
+		poster := nfo.Dir + "/poster.jpg"
+		keep := util.Exists(fanart) || util.Exists(poster)
+		err = util.SavePhotoContext(ctx, cover, fanart, cfg.Base.Proxy, !strings.EqualFold(path.Ext(cover), ".jpg"))
+		// 检查
+		if err == nil {
+			err = util.PosterCover(fanart, poster, cfg)
+		}
+		// 检查
+		if err != nil {
+			if !keep {
+				removeGenerated(fanart, poster)
+			}
+			return result, err
+		}
+		vm := *m
+		vm.FanArt, vm.Poster = fanart, poster
+		vs := NewVSMeta()
+		vs.Image = cfg.Image
+		vs.Proxy = cfg.Base.Proxy
+		bs = vs.ConvertContext(ctx, &vm)
+		// 删除图片
+		if !keep {
+			removeGenerated(fanart, poster)
+		}
+	} else {
+		bs = NewVSMeta().convertEmbedded(m, v.Poster, v.Backdrop)
+	}
+
+	// 写入目录中的所有vsmeta
+	files, err := filepath.Glob(filepath.Join(nfo.Dir, "*.vsmeta"))
+	// 检查
+	if err != nil {
+		return result, err
+	}
+	for _, file := range files {
+		if err = util.WriteFile(file, bs); err != nil {
+			return result, err
+		}
+	}
+
+	return result, writeVSMetaRecord(nfo.Dir, bs, record, result.Kept)
+}
+
+// 更新目录中已有的 vsmeta 文件
+func refreshVSMeta(ctx context.Context, m *Media, dir string, cfg *util.ConfigStruct) error {
+	// 查找vsmeta
+	files, err := filepath.Glob(filepath.Join(dir, "*.vsmeta"))
+	// 检查
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	// 使用图片绝对路径
+	vm := *m
+	vm.FanArt = ""
+	vm.Poster = ""
+	if util.Exists(dir + "/fanart.jpg") {
+		vm.FanArt = dir + "/fanart.jpg"
+	}
+	if util.Exists(dir + "/poster.jpg") {
+		vm.Poster = dir + "/poster.jpg"
+	}
+
+	// 转换
+	vs := NewVSMeta()
+	vs.Image = cfg.Image
+	vs.Proxy = cfg.Base.Proxy
+	bs := vs.ConvertContext(ctx, &vm)
+	for _, file := range files {
+		if err = util.WriteFile(file, bs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 将新刮削的数据合并到当前数据，跳过锁定的字段、手动修改过的字段及为空的新数据，
+// 返回有变化的字段名称及因手动修改而保留的字段名称。
+//
+// n Media结构体，传入新刮削的数据，
+// record scrapeRecord结构体，传入刮削记录，为 nil 时不识别手动修改。
+func (m *Media) merge(n *Media, record *scrapeRecord) (changed, kept []string) {
+	for _, f := range refreshFields {
+		// 锁定的字段
+		if m.locked(f.name) {
+			continue
+		}
+		// 手动修改过的字段
+		if record.edited(m, f.name) {
+			kept = append(kept, f.name)
+			continue
+		}
+		if f.merge(m, n) {
+			changed = append(changed, f.name)
+		}
+	}
+
+	// 没有番号时使用刮削结果
+	if m.Number == "" {
+		m.Number = n.Number
+		m.SortTitle = n.SortTitle
+	}
+
+	return changed, kept
+}
+
+// 检查字段是否被锁定，lockedfields 以 "|" 分隔，不区分大小写
+func (m *Media) locked(name string) bool {
+	// 没有锁定字段
+	if m.LockedFields == "" {
+		return false
+	}
+
+	// 字段名称及别名
+	var names []string
+	for _, f := range refreshFields {
+		if f.name == name {
+			names = append(append(names, f.name), f.aliases...)
+		}
+	}
+
+	for _, locked := range strings.Split(m.LockedFields, "|") {
+		locked = strings.TrimSpace(locked)
+		for _, n := range names {
+			if strings.EqualFold(locked, n) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// 评分保留一位小数
+func roundRating(rating float64) float64 {
+	return math.Round(rating*10) / 10
+}
+
+// 合并文字字段，新数据为空或相同时保持不变
+func mergeString(old *string, new string) bool {
+	new = strings.TrimSpace(new)
+	if new == "" || new == *old {
+		return false
+	}
+	*old = new
+
+	return true
+}
+
+// 合并列表字段，新数据为空或相同时保持不变
+func mergeInners(old *[]Inner, new []Inner) bool {
+	if len(new) == 0 || reflect.DeepEqual(*old, new) {
+		return false
+	}
+	*old = new
+
+	return true
+}
+
+// 比较演员列表，刮削结果中的演员顺序不固定，因此忽略顺序
+func sameActors(a, b []Actor) bool {
+	// 数量不同
+	if len(a) != len(b) {
+		return false
+	}
+
+	// 演员计数
+	count := make(map[Actor]int)
+	for _, actor := range a {
+		count[actor]++
+	}
+	for _, actor := range b {
+		if count[actor] == 0 {
+			return false
+		}
+		count[actor]--
+	}
+
+	return true
+}
+
+// 在新演员列表中保留原有演员的角色，按演员名称匹配
+func keepRoles(new, old []Actor) []Actor {
+	// 原有角色
+	roles := make(map[string]string)
+	for _, a := range old {
+		if a.Role != "" {
+			roles[a.Name] = a.Role
+		}
+	}
+
+	actors := make([]Actor, 0, len(new))
+	for _, a := range new {
+		if a.Role == "" {
+			a.Role = roles[a.Name]
+		}
+		actors = append(actors, a)
+	}
+
+	return actors
+}
+
+// 在新标签中保留原有的版本标签，如中文字幕、无码破解、4K
+func variantTags(new, old []Inner) []Inner {
+	// 没有新数据
+	if len(new) == 0 {
+		return nil
+	}
+
+	// 复制新标签
+	tags := append([]Inner(nil), new...)
+	for _, t := range old {
+		if t.Inner != "中文字幕" && t.Inner != "无码破解" && t.Inner != "4K" {
+			continue
+		}
+		exists := false
+		for _, n := range tags {
+			if n.Inner == t.Inner {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
+
+// 检查字段列表中是否包含指定字段
+func containsField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
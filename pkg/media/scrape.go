@@ -0,0 +1,151 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ylqjgm/AVMeta/pkg/scraper"
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// Attempt 刮削尝试记录，保存刮削来源及失败原因。
+type Attempt struct {
+	Site string // 刮削来源
+	Err  error  // 失败原因，刮削成功时为 nil
+}
+
+// Scrape 刮削给定番号并返回 Media 结构体及各刮削器的尝试记录，
+// 该方法不会下载图片，也不会移动或写入任何文件。
+//
+// ctx 上下文参数，传入刮削所使用的上下文，
+// code 字符串参数，传入要刮削的番号，
+// cfg ConfigStruct结构体，传入程序配置信息，
+// site 字符串参数，传入强制使用的刮削器名称，为空则按配置自动匹配。
+func Scrape(ctx context.Context, code string, cfg *util.ConfigStruct, site string) (*Media, []Attempt, error) {
+	// 转换番号为小写
+	code = strings.ToLower(code)
+
+	// 获取刮削器列表
+	entries, err := lookup(code, cfg, site)
+	// 检查
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 多源合并，强制指定刮削器时不合并
+	if cfg.Merge.Enable && site == "" {
+		return scrapeMerge(ctx, code, entries, cfg)
+	}
+
+	// 尝试记录
+	var attempts []Attempt
+
+	// 按优先级依次尝试
+	for _, entry := range entries {
+		// 上下文是否已结束
+		if err = ctx.Err(); err != nil {
+			return nil, attempts, err
+		}
+
+		// 获取刮削器上下文，刮削成功后才写入缓存
+		sctx, cancel := entry.Context(util.WithCacheSession(ctx))
+		// 实例化刮削对象
+		s := entry.New(cfg)
+		// 刮削
+		err = scraper.FetchContext(sctx, s, code)
+		// 记录
+		attempts = append(attempts, Attempt{Site: entry.Name, Err: err})
+		// 检查
+		if err != nil {
+			// 释放上下文
+			cancel()
+			continue
+		}
+
+		// 刮削并获取nfo对象
+		m, err := ParseMedia(s, entry.Name)
+		// 写入缓存
+		if err == nil {
+			util.CommitCache(sctx)
+		}
+		// 获取数据后释放上下文
+		cancel()
+
+		return m, attempts, err
+	}
+
+	return nil, attempts, err
+}
+
+// 获取刮削器列表，指定了刮削器时忽略其正则及禁用设置
+func lookup(code string, cfg *util.ConfigStruct, site string) ([]scraper.Entry, error) {
+	// 强制指定刮削器
+	if site != "" {
+		// 查找
+		entry, ok := scraper.Get(site, cfg)
+		// 检查
+		if !ok {
+			return nil, fmt.Errorf("%s: 刮削器不存在, 可用刮削器: %s", site, strings.Join(scraper.DefaultRegistry.Names(), ", "))
+		}
+
+		return []scraper.Entry{entry}, nil
+	}
+
+	// 获取匹配的刮削器列表
+	entries, err := scraper.Lookup(code, cfg)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	// 是否有可用刮削器
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: 没有可用的刮削器", code)
+	}
+
+	return entries, nil
+}
+
+// 多源合并刮削，查询所有匹配的刮削器并按字段优先级合并结果
+func scrapeMerge(ctx context.Context, code string, entries []scraper.Entry, cfg *util.ConfigStruct) (*Media, []Attempt, error) {
+	// 实例化合并刮削器
+	ms := scraper.NewMergeScraper(entries, cfg)
+	// 获取数据后释放上下文
+	defer ms.Close()
+	// 刮削
+	err := ms.FetchContext(ctx, code)
+
+	// 尝试记录
+	var attempts []Attempt
+	// 成功来源
+	sites := strings.Split(ms.Site(), "+")
+	// 按优先级整理
+	for _, entry := range entries {
+		// 是否失败
+		if e, ok := ms.Errors()[entry.Name]; ok {
+			attempts = append(attempts, Attempt{Site: entry.Name, Err: e})
+			continue
+		}
+		// 是否成功
+		for _, s := range sites {
+			if s == entry.Name {
+				attempts = append(attempts, Attempt{Site: entry.Name})
+				break
+			}
+		}
+	}
+
+	// 检查
+	if err != nil {
+		return nil, attempts, err
+	}
+
+	// 刮削并获取nfo对象
+	m, err := ParseMedia(ms, ms.Site())
+	// 写入缓存
+	if err == nil {
+		ms.CommitCache()
+	}
+
+	return m, attempts, err
+}
@@ -2,16 +2,64 @@ package media
 
 import (
 	"bytes"
-	"encoding/binary"
-	"math"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/ylqjgm/AVMeta/pkg/logs"
 	"github.com/ylqjgm/AVMeta/pkg/util"
 )
 
+// vsmeta 字段编号
+const (
+	vsmetaType      = 1  // 影片类型，1 为电影
+	vsmetaTitle     = 2  // 标题
+	vsmetaTitle2    = 3  // 原始标题
+	vsmetaTagline   = 4  // 副标题
+	vsmetaYear      = 5  // 年份
+	vsmetaDate      = 6  // 发行日期
+	vsmetaLocked    = 7  // 是否锁定
+	vsmetaSummary   = 8  // 简介
+	vsmetaMetaJSON  = 9  // 来源 json
+	vsmetaGroup     = 10 // 组数据，演员、导演、类型及编剧
+	vsmetaClass     = 11 // 级别
+	vsmetaRating    = 12 // 评分
+	vsmetaPoster    = 17 // 封面 Base64
+	vsmetaPosterMD5 = 18 // 封面 md5
+	vsmetaBackdrop  = 21 // 背景组数据
+)
+
+// vsmeta 组数据字段编号
+const (
+	vsmetaCast     = 1 // 演员
+	vsmetaDirector = 2 // 导演
+	vsmetaGenre    = 3 // 类型
+	vsmetaWriter   = 4 // 编剧，用于保存厂商
+)
+
+// vsmeta 背景组数据字段编号
+const (
+	vsmetaBackdropData = 1 // 背景 Base64
+	vsmetaBackdropMD5  = 2 // 背景 md5
+)
+
+// vsmeta 字段长度限制，超出最大字节数时截取为指定字符数
+const (
+	vsmetaTitleMax   = 255  // 标题最大字节数
+	vsmetaTitleLen   = 85   // 标题截取字符数
+	vsmetaSummaryMax = 4096 // 简介最大字节数
+	vsmetaSummaryLen = 1360 // 简介截取字符数
+)
+
 // VSMeta 群晖元数据结构体
 type VSMeta struct {
-	B bytes.Buffer
+	B     bytes.Buffer
+	Image util.ImageStruct // 内嵌图片配置，超出限制的封面及背景将被缩小
+	Proxy string           // 读取远程图片时使用的代理地址
 }
 
 // NewVSMeta 实例化一个VSMeta
@@ -19,215 +67,130 @@ func NewVSMeta() *VSMeta {
 	return &VSMeta{B: bytes.Buffer{}}
 }
 
+// 获取编码器
+func (v *VSMeta) encoder() *protoEncoder {
+	return &protoEncoder{buf: &v.B}
+}
+
 // ParseVSMeta 将刮削对象转换为 VSMeta 所需字节集
 //
-// s IScraper刮削接口，传入刮削对象
+// m Media结构体，传入刮削对象
 func (v *VSMeta) ParseVSMeta(m *Media) {
-	// 写入头部
-	v.writeTag(0x08)
+	e := v.encoder()
+
 	// 写入文件类型
-	v.writeTag(0x01)
+	e.writeVarint(vsmetaType, 1)
 	// 写入标题1
-	v.writeTag(0x12)
-	v.writeString(v.subStr(m.Title.Inner, 255, 85))
+	title := vsmetaSubStr(m.Title.Inner, vsmetaTitleMax, vsmetaTitleLen)
+	e.writeString(vsmetaTitle, title)
 	// 写入标题2
-	v.writeTag(0x1A)
-	v.writeString(v.subStr(m.Title.Inner, 255, 85))
+	e.writeString(vsmetaTitle2, title)
 	// 写入副标题
 	if m.SortTitle != "" {
-		v.writeTag(0x22)
-		v.writeString(v.subStr(m.SortTitle, 255, 85))
+		e.writeString(vsmetaTagline, vsmetaSubStr(m.SortTitle, vsmetaTitleMax, vsmetaTitleLen))
 	}
 
 	// 写入年份
-	if m.Year != "" {
-		year, _ := strconv.Atoi(m.Year)
-		v.writeTag(0x28)
-		v.writeInt(year)
-		v.writeTag(0x0F)
+	if year, err := strconv.ParseUint(m.Year, 10, 32); err == nil {
+		e.writeVarint(vsmetaYear, year)
 	}
 
 	// 写入日期
 	if m.Premiered != "" {
-		v.writeTag(0x32)
-		v.writeString(m.Premiered)
+		e.writeString(vsmetaDate, m.Premiered)
 	}
 	// 写入锁定
-	v.writeTag(0x38)
-	v.writeTag(0x01)
+	e.writeVarint(vsmetaLocked, 1)
 	// 写入简介
 	if m.Plot.Inner != "" {
-		v.writeTag(0x42)
-		v.writeString(v.subStr(m.Plot.Inner, 4096, 1360))
+		e.writeString(vsmetaSummary, vsmetaSubStr(m.Plot.Inner, vsmetaSummaryMax, vsmetaSummaryLen))
 	}
 	// 写入来源json
-	v.writeTag(0x4A)
-	v.writeString("null")
+	e.writeString(vsmetaMetaJSON, "null")
 
 	// 写入组数据
-	v.writeGroup(m)
-
-	// 写入级别
-	v.writeTag(0x5A)
-	v.writeString("XXX")
-	// 写入评分
-	v.writeTag(0x60)
-	v.writeInt(0)
-}
-
-// 写入标签
-func (v *VSMeta) writeTag(tag uint8) {
-	_ = binary.Write(&v.B, binary.LittleEndian, tag)
-}
-
-// 写入数字
-func (v *VSMeta) writeInt(num int) {
-	_ = binary.Write(&v.B, binary.LittleEndian, uint8(num))
-}
-
-// 写入字符串
-func (v *VSMeta) writeString(str string) {
-	v.writeBytes([]byte(str))
-}
-
-// 写入字节集
-func (v *VSMeta) writeBytes(bs []byte) {
-	// 先写入长度
-	v.writeLength(len(bs))
-	// 写入字节集
-	_ = binary.Write(&v.B, binary.LittleEndian, bs)
-}
-
-// 写入长度
-//
-// length整型，要写入的长度
-func (v *VSMeta) writeLength(length int) {
-	v.writeLengthByBuffer(length, &v.B)
-}
-
-// 写入长度到指定buffer
-func (v *VSMeta) writeLengthByBuffer(length int, buf *bytes.Buffer) {
-	// 只要长度大于128则循环处理
-	for length > 128 {
-		// 取余
-		_ = binary.Write(buf, binary.LittleEndian, byte((length%128)+128))
-		// 长度处理
-		length = int(math.Floor(float64(length) / float64(128)))
-	}
-	// 写入长度
-	_ = binary.Write(buf, binary.LittleEndian, byte(length))
-}
-
-// 写入组数据
-func (v *VSMeta) writeGroup(m *Media) {
-	// 定义字节集
-	var buf []byte
-	// 是否有演员
-	if len(m.Actor) > 0 {
-		// 循环演员信息
+	e.writeMessage(vsmetaGroup, func(g *protoEncoder) {
+		// 演员
 		for _, val := range m.Actor {
-			// 写入演员信息
-			buf = append(buf, uint8(0x0A), byte(len(val.Name)))
-			buf = append(buf, []byte(val.Name)...)
+			g.writeString(vsmetaCast, val.Name)
 		}
-	}
-	// 是否有导演
-	if m.Director.Inner != "" {
-		// 写入导演信息
-		buf = append(buf, uint8(0x12), byte(len(m.Director.Inner)))
-		buf = append(buf, []byte(m.Director.Inner)...)
-	}
-	// 是否有类型
-	if len(m.Genre) > 0 {
-		// 循环类型信息
+		// 导演
+		if m.Director.Inner != "" {
+			g.writeString(vsmetaDirector, m.Director.Inner)
+		}
+		// 类型
 		for _, val := range m.Genre {
-			// 写入类型信息
-			buf = append(buf, uint8(0x1A), byte(len(val.Inner)))
-			buf = append(buf, []byte(val.Inner)...)
+			g.writeString(vsmetaGenre, val.Inner)
 		}
-	}
-	// 是否有厂商
-	if m.Studio.Inner != "" {
-		// 写入编剧信息
-		buf = append(buf, uint8(0x22), byte(len(m.Studio.Inner)))
-		buf = append(buf, []byte(m.Studio.Inner)...)
-	}
-	// 是否有数据
-	if len(buf) > 0 {
-		// 写入标签
-		v.writeTag(0x52)
-		// 写入数据
-		v.writeBytes(buf)
-	}
+		// 厂商，写入编剧
+		if m.Studio.Inner != "" {
+			g.writeString(vsmetaWriter, m.Studio.Inner)
+		}
+	})
+
+	// 写入级别
+	e.writeString(vsmetaClass, "XXX")
+	// 写入评分
+	e.writeVarint(vsmetaRating, 0)
 }
 
 // 写入封面
 //
+// ctx 上下文，读取远程图片时使用，
 // file 字符串，封面图片路径
-func (v *VSMeta) writePoster(file string) {
+func (v *VSMeta) writePoster(ctx context.Context, file string) {
 	// 获取封面base64
-	poster, err := util.Base64(file)
+	poster, err := util.ImageBase64Context(ctx, file, v.Proxy, v.Image)
 	// 检查错误
 	if err != nil {
+		logs.Warning("封面: [%s] 无法写入 vsmeta, 错误原因: %s\n", filepath.Base(file), err)
 		return
 	}
-	// 获取封面md5
-	has := util.MD5String(poster)
 
-	// 写入封面
-	v.writeTag(0x8A)
-	v.writeTag(0x01)
-	v.writeString(poster)
+	v.embedPoster(poster)
+}
 
+// 写入 base64 编码的封面
+func (v *VSMeta) embedPoster(poster string) {
+	e := v.encoder()
+	// 写入封面
+	e.writeString(vsmetaPoster, poster)
 	// 写入md5
-	v.writeTag(0x92)
-	v.writeTag(0x01)
-	v.writeString(has)
+	e.writeString(vsmetaPosterMD5, util.MD5String(poster))
 }
 
 // 写入背景
 //
+// ctx 上下文，读取远程图片时使用，
 // file 字符串，背景图片路径
-func (v *VSMeta) writeFanart(file string) {
+func (v *VSMeta) writeFanart(ctx context.Context, file string) {
 	// 获取背景base64
-	fanart, err := util.Base64(file)
+	fanart, err := util.ImageBase64Context(ctx, file, v.Proxy, v.Image)
 	// 检查错误
 	if err != nil {
+		logs.Warning("背景: [%s] 无法写入 vsmeta, 错误原因: %s\n", filepath.Base(file), err)
 		return
 	}
-	// 获取背景md5
-	has := util.MD5String(fanart)
-
-	// 定义字节集
-	var buf []byte
-	length := len(fanart)
-	// 写入标签
-	buf = append(buf, uint8(0x0A))
-	// 写入长度
-	for length > 128 {
-		buf = append(buf, byte((length%128)+128))
-		length = int(math.Floor(float64(length) / float64(128)))
-	}
-	buf = append(buf, byte(length))
-	// 写入内容
-	buf = append(buf, []byte(fanart)...)
-	// 写入标签
-	buf = append(buf, uint8(0x12), uint8(0x20))
-	// 写入内容
-	buf = append(buf, []byte(has)...)
-
-	// 写入组2
-	v.writeTag(0xAA)
-	// 写入标签
-	v.writeTag(0x01)
-	// 写入内容
-	v.writeBytes(buf)
-}
-
-// 截取字符串
-func (v *VSMeta) subStr(str string, max, length int) string {
-	if len(str) > max {
-		return string([]rune(str)[:length])
+
+	v.embedFanart(fanart)
+}
+
+// 写入 base64 编码的背景
+func (v *VSMeta) embedFanart(fanart string) {
+	// 写入背景组数据
+	v.encoder().writeMessage(vsmetaBackdrop, func(g *protoEncoder) {
+		g.writeString(vsmetaBackdropData, fanart)
+		g.writeString(vsmetaBackdropMD5, util.MD5String(fanart))
+	})
+}
+
+// 截取字符串，超出最大字节数时截取为指定字符数
+func vsmetaSubStr(str string, max, length int) string {
+	if len(str) <= max {
+		return str
+	}
+	if r := []rune(str); len(r) > length {
+		return string(r[:length])
 	}
 
 	return str
@@ -235,18 +198,257 @@ func (v *VSMeta) subStr(str string, max, length int) string {
 
 // Convert 传入 nfo 对象转换为 vsmeta
 func (v *VSMeta) Convert(m *Media) []byte {
+	return v.ConvertContext(context.Background(), m)
+}
+
+// ConvertContext 携带上下文将 nfo 对象转换为 vsmeta，
+// 上下文被取消时将中断远程封面及背景图片的下载。
+//
+// ctx 上下文参数，传入读取远程图片所使用的上下文，
+// m Media结构体，传入刮削对象。
+func (v *VSMeta) ConvertContext(ctx context.Context, m *Media) []byte {
 	// 实例化VSMeta
 	vs := NewVSMeta()
+	vs.Image = v.Image
+	vs.Proxy = v.Proxy
 	// 解析为 vsmeta
 	vs.ParseVSMeta(m)
 	// 写入封面
 	if m.Poster != "" {
-		vs.writePoster(m.Poster)
+		vs.writePoster(ctx, m.Poster)
 	}
 	// 写入背景
 	if m.FanArt != "" {
-		vs.writeFanart(m.FanArt)
+		vs.writeFanart(ctx, m.FanArt)
 	}
 
 	return vs.B.Bytes()
 }
+
+// 将刮削对象转换为 vsmeta，并写入已有的封面及背景图片内容
+func (v *VSMeta) convertEmbedded(m *Media, poster, backdrop []byte) []byte {
+	// 实例化VSMeta
+	vs := NewVSMeta()
+	// 解析为 vsmeta
+	vs.ParseVSMeta(m)
+	// 写入封面
+	if len(poster) > 0 {
+		vs.embedPoster(base64.StdEncoding.EncodeToString(poster))
+	}
+	// 写入背景
+	if len(backdrop) > 0 {
+		vs.embedFanart(base64.StdEncoding.EncodeToString(backdrop))
+	}
+
+	return vs.B.Bytes()
+}
+
+// VSMetaFile 解析后的 vsmeta 文件内容
+type VSMetaFile struct {
+	Media    *Media // 元数据
+	Type     int    // 影片类型，1 为电影
+	Locked   bool   // 是否锁定
+	Poster   []byte // 封面图片
+	Backdrop []byte // 背景图片
+}
+
+// ReadVSMeta 读取 vsmeta 文件并解析为 VSMetaFile 结构体
+//
+// file 字符串参数，传入 vsmeta 文件路径。
+func ReadVSMeta(file string) (*VSMetaFile, error) {
+	// 读取文件
+	b, err := util.ReadFile(file)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeVSMeta(b)
+}
+
+// DecodeVSMeta 解析 vsmeta 内容，返回元数据及内嵌的封面、背景图片，
+// 未知字段将被忽略。
+//
+// b 字节集参数，传入 vsmeta 文件内容。
+func DecodeVSMeta(b []byte) (*VSMetaFile, error) {
+	// 解析字段
+	fields, err := decodeProto(b)
+	// 检查
+	if err != nil {
+		return nil, fmt.Errorf("vsmeta 格式错误, %s", err)
+	}
+
+	m := &Media{}
+	v := &VSMetaFile{Media: m}
+	for _, f := range fields {
+		switch f.num {
+		case vsmetaType:
+			v.Type = int(f.value)
+		case vsmetaTitle:
+			m.Title = Inner{Inner: string(f.data)}
+		case vsmetaTitle2:
+			if m.Title.Inner == "" {
+				m.Title = Inner{Inner: string(f.data)}
+			}
+		case vsmetaTagline:
+			m.SortTitle = string(f.data)
+		case vsmetaYear:
+			m.Year = strconv.FormatUint(f.value, 10)
+		case vsmetaDate:
+			m.Release = string(f.data)
+			m.Premiered = m.Release
+			m.Month = GetMonth(m.Release)
+		case vsmetaLocked:
+			v.Locked = f.value != 0
+		case vsmetaSummary:
+			m.Plot = Inner{Inner: string(f.data)}
+			m.Outline = m.Plot
+		case vsmetaGroup:
+			err = decodeVSMetaGroup(f.data, m)
+		case vsmetaClass:
+			m.Mpaa = string(f.data)
+		case vsmetaPoster:
+			v.Poster, err = decodeVSMetaImage(f.data)
+		case vsmetaBackdrop:
+			v.Backdrop, err = decodeVSMetaBackdrop(f.data)
+		}
+		// 检查
+		if err != nil {
+			return nil, fmt.Errorf("vsmeta 字段 %d 错误, %s", f.num, err)
+		}
+	}
+
+	// 番号，写入时副标题为番号
+	m.Number = strings.TrimSpace(m.SortTitle)
+	if m.Number == "" {
+		if parts := strings.Fields(m.Title.Inner); len(parts) > 0 {
+			m.Number = parts[0]
+		}
+	}
+	// 年份
+	if m.Year == "" || m.Year == "0" {
+		m.Year = GetYear(m.Release)
+	}
+
+	return v, nil
+}
+
+// 解析组数据
+func decodeVSMetaGroup(b []byte, m *Media) error {
+	// 解析字段
+	fields, err := decodeProto(b)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case vsmetaCast:
+			m.Actor = append(m.Actor, Actor{Name: string(f.data)})
+		case vsmetaDirector:
+			m.Director = Inner{Inner: string(f.data)}
+		case vsmetaGenre:
+			m.Genre = append(m.Genre, Inner{Inner: string(f.data)})
+		case vsmetaWriter:
+			m.Studio = Inner{Inner: string(f.data)}
+			m.Maker = m.Studio
+		}
+	}
+	// 标签
+	m.Tag = m.Genre
+
+	return nil
+}
+
+// 解析背景组数据
+func decodeVSMetaBackdrop(b []byte) ([]byte, error) {
+	// 解析字段
+	fields, err := decodeProto(b)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.num == vsmetaBackdropData {
+			return decodeVSMetaImage(f.data)
+		}
+	}
+
+	return nil, nil
+}
+
+// 解码 Base64 图片，忽略其中的换行
+func decodeVSMetaImage(b []byte) ([]byte, error) {
+	// 去除换行
+	s := strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, string(b))
+
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// ErrNfoExists nfo 文件已存在
+var ErrNfoExists = errors.New("nfo 文件已存在")
+
+// VSMetaToNfo 将 vsmeta 文件转换为同目录下的 nfo 文件，返回 nfo 文件路径，
+// 内嵌的封面及背景保存为 poster.jpg 及 fanart.jpg，已存在的图片不会被覆盖。
+//
+// file 字符串参数，传入 vsmeta 文件路径，
+// cfg ConfigStruct结构体，传入程序配置信息，
+// overwrite 布尔参数，传入是否覆盖已存在的 nfo 文件。
+func VSMetaToNfo(file string, cfg *util.ConfigStruct, overwrite bool) (string, error) {
+	// nfo 路径，vsmeta 名称为视频名称加 .vsmeta
+	dir := filepath.Dir(file)
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if util.IsVideoExt(name, cfg.Path.Exts) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	nfo := filepath.Join(dir, name+".nfo")
+	// 是否已存在
+	if !overwrite && util.Exists(nfo) {
+		return nfo, ErrNfoExists
+	}
+
+	// 解析
+	v, err := ReadVSMeta(file)
+	// 检查
+	if err != nil {
+		return nfo, err
+	}
+	m := v.Media
+
+	// 保存图片
+	images := []struct {
+		data []byte
+		name string
+		set  func(string)
+	}{
+		{v.Poster, "poster.jpg", func(s string) { m.Poster, m.Thumb = s, s }},
+		{v.Backdrop, "fanart.jpg", func(s string) { m.FanArt = s }},
+	}
+	for _, img := range images {
+		target := filepath.Join(dir, img.name)
+		if len(img.data) > 0 && !util.Exists(target) {
+			if err = util.WriteFile(target, img.data); err != nil {
+				return nfo, err
+			}
+		}
+		if util.Exists(target) {
+			img.set(img.name)
+		}
+	}
+
+	// 转换为XML
+	buff, err := mediaToXML(m, cfg.Media.Profile)
+	// 检查
+	if err != nil {
+		return nfo, err
+	}
+
+	return nfo, util.WriteFile(nfo, buff)
+}
@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 使用 go test ./pkg/media -update 重新生成 testdata 中的 vsmeta 文件
+var update = flag.Bool("update", false, "重新生成 testdata 中的 vsmeta 文件")
+
+// 测试用刮削对象
+func vsmetaMedia() *Media {
+	return &Media{
+		Title:     Inner{Inner: "SSIS-001 新人NO.1STYLE 河北彩花AVデビュー"},
+		SortTitle: "SSIS-001",
+		Number:    "SSIS-001",
+		Year:      "2021",
+		Premiered: "2021-02-19",
+		Plot:      Inner{Inner: "新人デビュー作品。"},
+		Director:  Inner{Inner: "紋℃"},
+		Studio:    Inner{Inner: "エスワン ナンバーワンスタイル"},
+		Genre:     []Inner{{Inner: "デビュー作品"}, {Inner: "単体作品"}},
+		Actor:     []Actor{{Name: "河北彩花"}},
+	}
+}
+
+// 包含大量演员及超长字段的刮削对象
+func vsmetaLongMedia() *Media {
+	m := vsmetaMedia()
+	m.Title = Inner{Inner: "SSIS-001 " + strings.Repeat("新人デビュー", 30)}
+	m.Plot = Inner{Inner: strings.Repeat("新人デビュー作品。", 200)}
+	m.Director = Inner{Inner: strings.Repeat("監督", 50)}
+	m.Studio = Inner{Inner: strings.Repeat("エスワン", 40)}
+	m.Actor = nil
+	for i := 1; i <= 60; i++ {
+		m.Actor = append(m.Actor, Actor{Name: fmt.Sprintf("%s%02d", strings.Repeat("女優", i*2), i)})
+	}
+	m.Genre = append(m.Genre, Inner{Inner: strings.Repeat("ジャンル", 30)})
+
+	return m
+}
+
+func TestVSMetaGolden(t *testing.T) {
+	dir := filepath.Join("testdata", "vsmeta")
+
+	tests := []struct {
+		name   string
+		m      *Media
+		images bool
+	}{
+		{name: "basic", m: vsmetaMedia()},
+		{name: "images", m: vsmetaMedia(), images: true},
+		{name: "long", m: vsmetaLongMedia()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var poster, fanart []byte
+			if tt.images {
+				tt.m.Poster = filepath.Join(dir, "poster.jpg")
+				tt.m.FanArt = filepath.Join(dir, "fanart.jpg")
+				poster, _ = ioutil.ReadFile(tt.m.Poster)
+				fanart, _ = ioutil.ReadFile(tt.m.FanArt)
+			}
+
+			// 转换
+			got := NewVSMeta().Convert(tt.m)
+
+			// 与 testdata 比较
+			golden := filepath.Join(dir, tt.name+".vsmeta")
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Convert() 与 %s 不一致", golden)
+			}
+
+			// 解析
+			v, err := DecodeVSMeta(got)
+			if err != nil {
+				t.Fatalf("DecodeVSMeta() error = %v", err)
+			}
+			d := v.Media
+			if d.Title.Inner != vsmetaSubStr(tt.m.Title.Inner, vsmetaTitleMax, vsmetaTitleLen) {
+				t.Errorf("Title = %q", d.Title.Inner)
+			}
+			if d.Plot.Inner != vsmetaSubStr(tt.m.Plot.Inner, vsmetaSummaryMax, vsmetaSummaryLen) {
+				t.Errorf("Plot = %q", d.Plot.Inner)
+			}
+			if d.Number != tt.m.Number || d.Year != tt.m.Year || d.Release != tt.m.Premiered {
+				t.Errorf("Number, Year, Release = %q, %q, %q", d.Number, d.Year, d.Release)
+			}
+			if d.Director != tt.m.Director || d.Studio != tt.m.Studio {
+				t.Errorf("Director, Studio = %q, %q", d.Director.Inner, d.Studio.Inner)
+			}
+			if !reflect.DeepEqual(d.Actor, tt.m.Actor) {
+				t.Errorf("Actor = %v, want %v", d.Actor, tt.m.Actor)
+			}
+			if !reflect.DeepEqual(d.Genre, tt.m.Genre) {
+				t.Errorf("Genre = %v, want %v", d.Genre, tt.m.Genre)
+			}
+			if !bytes.Equal(v.Poster, poster) || !bytes.Equal(v.Backdrop, fanart) {
+				t.Errorf("Poster, Backdrop 长度 = %d, %d, want %d, %d", len(v.Poster), len(v.Backdrop), len(poster), len(fanart))
+			}
+		})
+	}
+}
+
+func TestVSMetaLength(t *testing.T) {
+	// 变长整数的临界长度
+	for _, n := range []int{0, 1, 127, 128, 255, 256, 16383, 16384, 2097152} {
+		m := &Media{Title: Inner{Inner: "SSIS-001"}, Actor: []Actor{{Name: strings.Repeat("a", n)}}}
+
+		v, err := DecodeVSMeta(NewVSMeta().Convert(m))
+		if err != nil {
+			t.Fatalf("长度 %d: DecodeVSMeta() error = %v", n, err)
+		}
+		if len(v.Media.Actor) != 1 || len(v.Media.Actor[0].Name) != n {
+			t.Errorf("长度 %d: Actor = %d 个", n, len(v.Media.Actor))
+		}
+	}
+}
+
+func TestVSMetaConvertEmbedded(t *testing.T) {
+	dir := filepath.Join("testdata", "vsmeta")
+	m := vsmetaMedia()
+	m.Poster = filepath.Join(dir, "poster.jpg")
+	m.FanArt = filepath.Join(dir, "fanart.jpg")
+
+	// 原有的 vsmeta
+	v, err := DecodeVSMeta(NewVSMeta().Convert(m))
+	if err != nil {
+		t.Fatalf("DecodeVSMeta() error = %v", err)
+	}
+
+	// 修改标题后保留内嵌图片
+	v.Media.Title = Inner{Inner: "SSIS-001 新しいタイトル"}
+	got, err := DecodeVSMeta(NewVSMeta().convertEmbedded(v.Media, v.Poster, v.Backdrop))
+	if err != nil {
+		t.Fatalf("DecodeVSMeta() error = %v", err)
+	}
+	if got.Media.Title != v.Media.Title {
+		t.Errorf("Title = %q, want %q", got.Media.Title.Inner, v.Media.Title.Inner)
+	}
+	if !bytes.Equal(got.Poster, v.Poster) || !bytes.Equal(got.Backdrop, v.Backdrop) {
+		t.Errorf("Poster, Backdrop 长度 = %d, %d, want %d, %d", len(got.Poster), len(got.Backdrop), len(v.Poster), len(v.Backdrop))
+	}
+	if len(got.Poster) == 0 || len(got.Backdrop) == 0 {
+		t.Error("内嵌图片为空")
+	}
+}
@@ -0,0 +1,12 @@
+/*
+Package probe 视频文件信息读取包。
+
+通过本包，可在不依赖 ffmpeg 的情况下读取视频文件的容器头部信息，
+获取时长、分辨率、视频编码、音频编码及声道数等数据。
+
+目前支持 MP4/MOV（moov 中的 mvhd、tkhd、mdhd、hdlr、stsd）、
+Matroska/WebM（EBML 中的 Info 及 Tracks）以及 MPEG-TS/M2TS（PAT、PMT、PCR，
+分辨率从 H.264/H.265 SPS 或 MPEG-2 序列头中解析）三类容器，
+其余格式将返回 ErrUnsupported。
+*/
+package probe
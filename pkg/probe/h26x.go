@@ -0,0 +1,257 @@
+package probe
+
+// High 及以上档次，SPS 中包含色度格式等字段
+var avcHighProfiles = map[uint64]bool{
+	100: true, 110: true, 122: true, 244: true, 44: true,
+	83: true, 86: true, 118: true, 128: true, 138: true, 139: true, 134: true, 135: true,
+}
+
+// 位读取器，读取超出范围时返回 0 并记录错误
+type bitReader struct {
+	b   []byte
+	pos int
+	err bool
+}
+
+// 读取 n 位无符号整数
+func (r *bitReader) u(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.b)*8 {
+			r.err = true
+			return 0
+		}
+		v = v<<1 | uint64(r.b[r.pos/8]>>(7-uint(r.pos%8))&0x01)
+		r.pos++
+	}
+
+	return v
+}
+
+// 读取无符号指数哥伦布编码
+func (r *bitReader) ue() uint64 {
+	// 前导零个数
+	zeros := 0
+	for r.u(1) == 0 {
+		if r.err || zeros > 32 {
+			r.err = true
+			return 0
+		}
+		zeros++
+	}
+
+	return 1<<uint(zeros) - 1 + r.u(zeros)
+}
+
+// 读取有符号指数哥伦布编码
+func (r *bitReader) se() int64 {
+	v := r.ue()
+	if v&0x01 != 0 {
+		return int64(v+1) / 2
+	}
+
+	return -int64(v / 2)
+}
+
+// 按起始码拆分 NAL 单元
+func splitNAL(b []byte) [][]byte {
+	var nals [][]byte
+	start := -1
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] != 0 || b[i+1] != 0 || b[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			nals = append(nals, b[start:i])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(b) {
+		nals = append(nals, b[start:])
+	}
+
+	return nals
+}
+
+// 去除防竞争字节 0x000003
+func unescapeRBSP(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 0x03 {
+			zeros = 0
+			continue
+		}
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// 根据色度格式获取裁剪单位
+func chromaSubsampling(chroma uint64) (int, int) {
+	switch chroma {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+
+	return 1, 1
+}
+
+// 解析 H.264 SPS，返回宽度及高度，b 为去除 NAL 头后的内容
+func parseAVCSPS(b []byte) (int, int) {
+	r := &bitReader{b: unescapeRBSP(b)}
+
+	// 档次、约束标识及级别
+	profile := r.u(8)
+	r.u(16)
+	r.ue()
+
+	// 色度格式，默认为 4:2:0
+	chroma := uint64(1)
+	separate := uint64(0)
+	if avcHighProfiles[profile] {
+		chroma = r.ue()
+		if chroma == 3 {
+			separate = r.u(1)
+		}
+		r.ue()
+		r.ue()
+		r.u(1)
+		// 缩放矩阵
+		if r.u(1) == 1 {
+			count := 8
+			if chroma == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				if r.u(1) == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				last, next := int64(8), int64(8)
+				for j := 0; j < size; j++ {
+					if next != 0 {
+						next = (last + r.se() + 256) % 256
+					}
+					if next != 0 {
+						last = next
+					}
+				}
+			}
+		}
+	}
+
+	// 帧序号及图像顺序
+	r.ue()
+	switch r.ue() {
+	case 0:
+		r.ue()
+	case 1:
+		r.u(1)
+		r.se()
+		r.se()
+		for n := r.ue(); n > 0 && !r.err; n-- {
+			r.se()
+		}
+	}
+	r.ue()
+	r.u(1)
+
+	// 宏块数量
+	width := int(r.ue()+1) * 16
+	height := int(r.ue()+1) * 16
+	frameMbsOnly := int(r.u(1))
+	if frameMbsOnly == 0 {
+		r.u(1)
+	}
+	height *= 2 - frameMbsOnly
+	r.u(1)
+
+	// 裁剪
+	if r.u(1) == 1 {
+		unitX, unitY := chromaSubsampling(chroma)
+		if chroma == 0 || separate == 1 {
+			unitX, unitY = 1, 1
+		}
+		unitY *= 2 - frameMbsOnly
+		left, right, top, bottom := int(r.ue()), int(r.ue()), int(r.ue()), int(r.ue())
+		width -= unitX * (left + right)
+		height -= unitY * (top + bottom)
+	}
+
+	if r.err || width <= 0 || height <= 0 {
+		return 0, 0
+	}
+
+	return width, height
+}
+
+// 解析 H.265 SPS，返回宽度及高度，b 为去除 NAL 头后的内容
+func parseHEVCSPS(b []byte) (int, int) {
+	r := &bitReader{b: unescapeRBSP(b)}
+
+	// 视频参数集 ID、子层数量
+	r.u(4)
+	subLayers := int(r.u(3))
+	r.u(1)
+
+	// 档次、层级及级别
+	r.u(88)
+	r.u(8)
+	profilePresent := make([]bool, subLayers)
+	levelPresent := make([]bool, subLayers)
+	for i := 0; i < subLayers; i++ {
+		profilePresent[i] = r.u(1) == 1
+		levelPresent[i] = r.u(1) == 1
+	}
+	if subLayers > 0 {
+		for i := subLayers; i < 8; i++ {
+			r.u(2)
+		}
+	}
+	for i := 0; i < subLayers; i++ {
+		if profilePresent[i] {
+			r.u(88)
+		}
+		if levelPresent[i] {
+			r.u(8)
+		}
+	}
+
+	// 序列参数集 ID 及色度格式
+	r.ue()
+	chroma := r.ue()
+	if chroma == 3 {
+		r.u(1)
+	}
+
+	// 尺寸
+	width := int(r.ue())
+	height := int(r.ue())
+
+	// 裁剪
+	if r.u(1) == 1 {
+		unitX, unitY := chromaSubsampling(chroma)
+		left, right, top, bottom := int(r.ue()), int(r.ue()), int(r.ue()), int(r.ue())
+		width -= unitX * (left + right)
+		height -= unitY * (top + bottom)
+	}
+
+	if r.err || width <= 0 || height <= 0 {
+		return 0, 0
+	}
+
+	return width, height
+}
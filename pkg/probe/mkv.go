@@ -0,0 +1,291 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// EBML 元素 ID
+const (
+	ebmlHeader    = 0x1a45dfa3
+	ebmlSegment   = 0x18538067
+	ebmlInfo      = 0x1549a966
+	ebmlTracks    = 0x1654ae6b
+	ebmlCluster   = 0x1f43b675
+	ebmlScale     = 0x2ad7b1
+	ebmlDuration  = 0x4489
+	ebmlTrack     = 0xae
+	ebmlTrackType = 0x83
+	ebmlCodecID   = 0x86
+	ebmlLanguage  = 0x22b59c
+	ebmlVideo     = 0xe0
+	ebmlAudio     = 0xe1
+	ebmlWidth     = 0xb0
+	ebmlHeight    = 0xba
+	ebmlChannels  = 0x9f
+)
+
+// 未知大小，用于直播录制等无法预先确定大小的元素
+const ebmlUnknown = math.MaxUint64
+
+// Info 及 Tracks 最大读取大小
+const maxEBMLSize = 16 << 20
+
+// Matroska 编码名称，按前缀匹配
+var mkvCodecs = []struct {
+	prefix string
+	codec  string
+}{
+	{"V_MPEG4/ISO/AVC", "h264"},
+	{"V_MPEGH/ISO/HEVC", "hevc"},
+	{"V_MPEG4/", "mpeg4"},
+	{"V_MPEG2", "mpeg2video"},
+	{"V_MPEG1", "mpeg1video"},
+	{"V_AV1", "av1"},
+	{"V_VP8", "vp8"},
+	{"V_VP9", "vp9"},
+	{"V_REAL/", "realvideo"},
+	{"A_AAC", "aac"},
+	{"A_AC3", "ac3"},
+	{"A_EAC3", "eac3"},
+	{"A_DTS", "dts"},
+	{"A_TRUEHD", "truehd"},
+	{"A_OPUS", "opus"},
+	{"A_VORBIS", "vorbis"},
+	{"A_FLAC", "flac"},
+	{"A_MPEG/L3", "mp3"},
+	{"A_MPEG/L2", "mp2"},
+	{"A_PCM/", "pcm"},
+}
+
+// 读取 Matroska/WebM 信息
+func probeMKV(r io.ReaderAt, size int64) (*Info, error) {
+	info := &Info{Container: ContainerMKV}
+
+	// 查找 Segment
+	off := int64(0)
+	var seg, segEnd int64
+	for off < size {
+		id, length, dataOff, err := readElement(r, off, size)
+		// 检查
+		if err != nil {
+			return nil, err
+		}
+		if id == ebmlSegment {
+			seg, segEnd = dataOff, size
+			if length != ebmlUnknown && dataOff+int64(length) < size {
+				segEnd = dataOff + int64(length)
+			}
+			break
+		}
+		if length == ebmlUnknown {
+			break
+		}
+		off = dataOff + int64(length)
+	}
+	if seg == 0 {
+		return nil, fmt.Errorf("mkv 缺少 Segment")
+	}
+
+	// 读取 Info 及 Tracks，位于第一个 Cluster 之前
+	var hasInfo, hasTracks bool
+	for off = seg; off < segEnd && !(hasInfo && hasTracks); {
+		id, length, dataOff, err := readElement(r, off, segEnd)
+		// 检查
+		if err != nil {
+			return nil, err
+		}
+		if id == ebmlCluster || length == ebmlUnknown {
+			break
+		}
+
+		switch id {
+		case ebmlInfo, ebmlTracks:
+			if length > maxEBMLSize {
+				return nil, fmt.Errorf("mkv 元素过大: %d", length)
+			}
+			b, err := readAt(r, dataOff, int(length), segEnd)
+			// 检查
+			if err != nil {
+				return nil, err
+			}
+			if id == ebmlInfo {
+				hasInfo = true
+				parseMKVInfo(b, info)
+			} else {
+				hasTracks = true
+				parseMKVTracks(b, info)
+			}
+		}
+
+		off = dataOff + int64(length)
+	}
+	if !hasTracks {
+		return nil, fmt.Errorf("mkv 缺少 Tracks")
+	}
+
+	return info, nil
+}
+
+// 读取元素头，返回元素 ID、内容大小及内容位置
+func readElement(r io.ReaderAt, off, size int64) (uint64, uint64, int64, error) {
+	// 读取元素头，ID 最长 4 字节，大小最长 8 字节
+	head, err := readAt(r, off, 12, size)
+	// 检查
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	id, n := readVint(head, true)
+	if n == 0 {
+		return 0, 0, 0, fmt.Errorf("mkv 元素 ID 错误")
+	}
+	length, m := readVint(head[n:], false)
+	if m == 0 {
+		return 0, 0, 0, fmt.Errorf("mkv 元素大小错误")
+	}
+
+	return id, length, off + int64(n+m), nil
+}
+
+// 遍历元素内容中的子元素
+func eachElement(b []byte, fn func(id uint64, b []byte)) {
+	for len(b) > 0 {
+		id, n := readVint(b, true)
+		if n == 0 {
+			return
+		}
+		length, m := readVint(b[n:], false)
+		if m == 0 || length > uint64(len(b)-n-m) {
+			return
+		}
+		b = b[n+m:]
+		fn(id, b[:length])
+		b = b[length:]
+	}
+}
+
+// 读取变长整数，返回数值及长度，id 为 true 时保留长度标识位
+func readVint(b []byte, id bool) (uint64, int) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0
+	}
+
+	// 长度由首字节前导零决定
+	n := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > len(b) || (id && n > 4) {
+		return 0, 0
+	}
+
+	// 读取数值
+	v := uint64(b[0])
+	if !id {
+		v &= uint64(0xff >> uint(n))
+	}
+	unknown := v == uint64(0xff>>uint(n))
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+		unknown = unknown && c == 0xff
+	}
+	if !id && unknown {
+		return ebmlUnknown, n
+	}
+
+	return v, n
+}
+
+// 解析 Info，获取时长
+func parseMKVInfo(b []byte, info *Info) {
+	// 时间刻度，默认为 1 毫秒
+	scale := uint64(1000000)
+	var duration float64
+
+	eachElement(b, func(id uint64, b []byte) {
+		switch id {
+		case ebmlScale:
+			scale = be(b)
+		case ebmlDuration:
+			switch len(b) {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(b))
+			}
+		}
+	})
+
+	info.Duration = time.Duration(duration * float64(scale))
+}
+
+// 解析 Tracks，获取视频流及音频流
+func parseMKVTracks(b []byte, info *Info) {
+	eachElement(b, func(id uint64, b []byte) {
+		if id != ebmlTrack {
+			return
+		}
+
+		var (
+			typ           uint64
+			codec         string
+			width, height int
+			// 声道数，默认为 1
+			channels = 1
+			// 语言，默认为 eng
+			lang = "eng"
+		)
+		eachElement(b, func(id uint64, b []byte) {
+			switch id {
+			case ebmlTrackType:
+				typ = be(b)
+			case ebmlCodecID:
+				codec = mkvCodec(string(b))
+			case ebmlLanguage:
+				lang = strings.TrimRight(string(b), "\x00")
+			case ebmlVideo:
+				eachElement(b, func(id uint64, b []byte) {
+					switch id {
+					case ebmlWidth:
+						width = int(be(b))
+					case ebmlHeight:
+						height = int(be(b))
+					}
+				})
+			case ebmlAudio:
+				eachElement(b, func(id uint64, b []byte) {
+					if id == ebmlChannels {
+						channels = int(be(b))
+					}
+				})
+			}
+		})
+		if lang == "und" {
+			lang = ""
+		}
+
+		// 1 为视频，2 为音频
+		switch typ {
+		case 1:
+			info.Video = append(info.Video, VideoStream{Codec: codec, Width: width, Height: height})
+		case 2:
+			info.Audio = append(info.Audio, AudioStream{Codec: codec, Channels: channels, Language: lang})
+		}
+	})
+}
+
+// 转换 Matroska 编码名称
+func mkvCodec(id string) string {
+	id = strings.TrimRight(id, "\x00")
+	for _, c := range mkvCodecs {
+		if strings.HasPrefix(id, c.prefix) {
+			return c.codec
+		}
+	}
+
+	return strings.ToLower(id)
+}
@@ -0,0 +1,105 @@
+package probe
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// 未知大小，8 字节及 1 字节形式
+var (
+	mkvUnknown8 = []byte{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	mkvUnknown1 = []byte{0xff}
+)
+
+// 元素 ID，按最短长度写入
+func mkvID(id uint64) []byte {
+	switch {
+	case id > 0xffffff:
+		return u32(uint32(id))
+	case id > 0xffff:
+		return u32(uint32(id))[1:]
+	case id > 0xff:
+		return u16(uint16(id))
+	}
+	return []byte{byte(id)}
+}
+
+// 生成元素，大小使用 8 字节变长整数
+func mkvElement(id uint64, data ...[]byte) []byte {
+	body := join(data...)
+	size := u64(uint64(len(body)))
+	size[0] = 0x01
+	return join(mkvID(id), size, body)
+}
+
+// 生成未知大小的元素
+func mkvUnknownElement(id uint64, size []byte, data ...[]byte) []byte {
+	return join(mkvID(id), size, join(data...))
+}
+
+// 生成无符号整数元素
+func mkvUint(id, v uint64) []byte {
+	b := u64(v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return mkvElement(id, b)
+}
+
+// 生成轨道
+func mkvTrack(typ uint64, codec, lang string, settings []byte) []byte {
+	data := [][]byte{mkvUint(ebmlTrackType, typ), mkvElement(ebmlCodecID, []byte(codec))}
+	if lang != "" {
+		data = append(data, mkvElement(ebmlLanguage, []byte(lang)))
+	}
+	return mkvElement(ebmlTrack, append(data, settings)...)
+}
+
+// 生成文件，Segment 及 Cluster 均为未知大小
+func mkvFile(segmentSize []byte, info, tracks []byte) []byte {
+	header := mkvElement(ebmlHeader, mkvElement(0x4282, []byte("matroska")))
+	cluster := mkvUnknownElement(ebmlCluster, mkvUnknown8, mkvUint(0xe7, 0), make([]byte, 64))
+	return join(header, mkvUnknownElement(ebmlSegment, segmentSize, info, tracks, cluster))
+}
+
+func TestProbeMKV(t *testing.T) {
+	runProbeTests(t, []probeTest{
+		{
+			// 直播录制，Segment 为 8 字节未知大小，时长为 64 位浮点数
+			name: "unknown8",
+			fixture: mkvFile(mkvUnknown8,
+				mkvElement(ebmlInfo, mkvUint(ebmlScale, 1000000), mkvElement(ebmlDuration, u64(math.Float64bits(7200500)))),
+				mkvElement(ebmlTracks,
+					mkvTrack(1, "V_MPEG4/ISO/AVC", "", mkvElement(ebmlVideo, mkvUint(ebmlWidth, 1920), mkvUint(ebmlHeight, 1080))),
+					mkvTrack(2, "A_AAC", "jpn", mkvElement(ebmlAudio, mkvUint(ebmlChannels, 6))),
+				),
+			),
+			want: &Info{
+				Container: ContainerMKV,
+				Duration:  2*time.Hour + 500*time.Millisecond,
+				Video:     []VideoStream{{Codec: "h264", Width: 1920, Height: 1080}},
+				Audio:     []AudioStream{{Codec: "aac", Channels: 6, Language: "jpn"}},
+			},
+			resolution: "1080p",
+		},
+		{
+			// Segment 为 1 字节未知大小，时长为 32 位浮点数，声道及语言使用默认值
+			name: "unknown1",
+			fixture: mkvFile(mkvUnknown1,
+				mkvElement(ebmlInfo, mkvUint(ebmlScale, 1000000000), mkvElement(ebmlDuration, u32(math.Float32bits(600)))),
+				mkvElement(ebmlTracks,
+					mkvTrack(1, "V_MPEGH/ISO/HEVC", "und", mkvElement(ebmlVideo, mkvUint(ebmlWidth, 3840), mkvUint(ebmlHeight, 1600))),
+					mkvTrack(2, "A_OPUS", "", nil),
+				),
+			),
+			want: &Info{
+				Container: ContainerMKV,
+				Duration:  10 * time.Minute,
+				Video:     []VideoStream{{Codec: "hevc", Width: 3840, Height: 1600}},
+				Audio:     []AudioStream{{Codec: "opus", Channels: 1, Language: "eng"}},
+			},
+			resolution: "2160p",
+		},
+	})
+}
@@ -0,0 +1,308 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// moov 最大读取大小
+const maxMoovSize = 64 << 20
+
+// MP4 编码名称
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hev1": "hevc",
+	"hvc1": "hevc",
+	"av01": "av1",
+	"vp08": "vp8",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"Opus": "opus",
+	"fLaC": "flac",
+	"alac": "alac",
+	".mp3": "mp3",
+	"sowt": "pcm",
+	"twos": "pcm",
+	"lpcm": "pcm",
+}
+
+// 是否为 MP4/MOV 文件，第一个 box 为 ftyp 或 QuickTime 常见 box
+func isMP4(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+
+	switch string(head[4:8]) {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide", "pnot":
+		return true
+	}
+
+	return false
+}
+
+// 读取 MP4/MOV 信息
+func probeMP4(r io.ReaderAt, size int64) (*Info, error) {
+	// 查找 moov
+	moov, err := findMoov(r, size)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Container: ContainerMP4}
+	// 全局时长
+	var timescale, duration uint64
+	// 各轨道时长，全局时长缺失时使用
+	var trackDuration time.Duration
+
+	eachBox(moov, func(typ string, b []byte) {
+		switch typ {
+		case "mvhd":
+			timescale, duration = parseMvhd(b)
+		case "mvex":
+			// 分片文件的时长
+			eachBox(b, func(typ string, b []byte) {
+				if typ == "mehd" && duration == 0 && len(b) >= 8 {
+					if b[0] == 1 && len(b) >= 12 {
+						duration = be(b[4:12])
+					} else {
+						duration = be(b[4:8])
+					}
+				}
+			})
+		case "trak":
+			if d := parseTrak(b, info); d > trackDuration {
+				trackDuration = d
+			}
+		}
+	})
+
+	// 时长
+	info.Duration = scaleDuration(duration, timescale)
+	if info.Duration == 0 {
+		info.Duration = trackDuration
+	}
+
+	return info, nil
+}
+
+// 在顶层 box 中查找 moov 并读取内容
+func findMoov(r io.ReaderAt, size int64) ([]byte, error) {
+	for off := int64(0); off+8 <= size; {
+		// 读取 box 头
+		head, err := readAt(r, off, 16, size)
+		// 检查
+		if err != nil {
+			return nil, err
+		}
+		boxSize, headSize := int64(be(head[0:4])), int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - off
+		case 1:
+			if len(head) < 16 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			boxSize, headSize = int64(be(head[8:16])), 16
+		}
+		if boxSize < headSize {
+			return nil, fmt.Errorf("mp4 box 大小错误: %d", boxSize)
+		}
+
+		// 找到 moov
+		if string(head[4:8]) == "moov" {
+			if boxSize-headSize > maxMoovSize {
+				return nil, fmt.Errorf("mp4 moov 过大: %d", boxSize)
+			}
+			return readAt(r, off+headSize, int(boxSize-headSize), size)
+		}
+
+		off += boxSize
+	}
+
+	return nil, fmt.Errorf("mp4 缺少 moov")
+}
+
+// 遍历 box 内容中的子 box
+func eachBox(b []byte, fn func(typ string, b []byte)) {
+	for len(b) >= 8 {
+		boxSize, headSize := be(b[0:4]), uint64(8)
+		switch boxSize {
+		case 0:
+			boxSize = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return
+			}
+			boxSize, headSize = be(b[8:16]), 16
+		}
+		if boxSize < headSize || boxSize > uint64(len(b)) {
+			return
+		}
+
+		fn(string(b[4:8]), b[headSize:boxSize])
+		b = b[boxSize:]
+	}
+}
+
+// 解析 mvhd，返回时间刻度及时长
+func parseMvhd(b []byte) (uint64, uint64) {
+	// 版本 1 使用 64 位时间
+	if len(b) >= 32 && b[0] == 1 {
+		return be(b[20:24]), unknownDuration(be(b[24:32]), 64)
+	}
+	if len(b) >= 20 {
+		return be(b[12:16]), unknownDuration(be(b[16:20]), 32)
+	}
+
+	return 0, 0
+}
+
+// 时长为全 1 时表示未知
+func unknownDuration(d uint64, bits uint) uint64 {
+	if d == 1<<bits-1 {
+		return 0
+	}
+
+	return d
+}
+
+// 按时间刻度换算时长
+func scaleDuration(duration, timescale uint64) time.Duration {
+	if timescale == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// 解析轨道并加入视频信息，返回轨道时长
+func parseTrak(b []byte, info *Info) time.Duration {
+	var (
+		// 轨道类型
+		handler string
+		// 显示尺寸
+		width, height int
+		// 轨道时长
+		duration time.Duration
+		// 语言
+		lang string
+		// 第一个样本描述
+		format string
+		entry  []byte
+	)
+
+	eachBox(b, func(typ string, b []byte) {
+		switch typ {
+		case "tkhd":
+			width, height = parseTkhd(b)
+		case "mdia":
+			eachBox(b, func(typ string, b []byte) {
+				switch typ {
+				case "mdhd":
+					duration, lang = parseMdhd(b)
+				case "hdlr":
+					if len(b) >= 12 {
+						handler = string(b[8:12])
+					}
+				case "minf":
+					eachBox(b, func(typ string, b []byte) {
+						if typ != "stbl" {
+							return
+						}
+						eachBox(b, func(typ string, b []byte) {
+							if typ == "stsd" && len(b) >= 8 {
+								eachBox(b[8:], func(typ string, b []byte) {
+									if format == "" {
+										format, entry = typ, b
+									}
+								})
+							}
+						})
+					})
+				}
+			})
+		}
+	})
+
+	// 编码名称
+	codec, ok := mp4Codecs[format]
+	if !ok {
+		codec = format
+	}
+
+	switch handler {
+	case "vide":
+		// 显示尺寸缺失时使用样本描述中的尺寸
+		if (width == 0 || height == 0) && len(entry) >= 28 {
+			width, height = int(be(entry[24:26])), int(be(entry[26:28]))
+		}
+		info.Video = append(info.Video, VideoStream{Codec: codec, Width: width, Height: height})
+	case "soun":
+		info.Audio = append(info.Audio, AudioStream{Codec: codec, Channels: mp4Channels(entry), Language: lang})
+	}
+
+	return duration
+}
+
+// 解析 tkhd，返回显示宽度及高度
+func parseTkhd(b []byte) (int, int) {
+	// 尺寸位于最后 8 个字节，16.16 定点数
+	off := 76
+	if len(b) > 0 && b[0] == 1 {
+		off = 88
+	}
+	if len(b) < off+8 {
+		return 0, 0
+	}
+
+	return int(be(b[off:off+4]) >> 16), int(be(b[off+4:off+8]) >> 16)
+}
+
+// 解析 mdhd，返回轨道时长及语言
+func parseMdhd(b []byte) (time.Duration, string) {
+	// 时间刻度、时长及语言位置
+	var timescale, duration uint64
+	var off int
+	switch {
+	case len(b) >= 34 && b[0] == 1:
+		timescale, duration, off = be(b[20:24]), unknownDuration(be(b[24:32]), 64), 32
+	case len(b) >= 22:
+		timescale, duration, off = be(b[12:16]), unknownDuration(be(b[16:20]), 32), 20
+	default:
+		return 0, ""
+	}
+
+	// 语言为 3 个 5 位字符，小于 0x400 时为 QuickTime 语言代码
+	code := binary.BigEndian.Uint16(b[off : off+2])
+	lang := string([]byte{
+		byte(code>>10&0x1f) + 0x60,
+		byte(code>>5&0x1f) + 0x60,
+		byte(code&0x1f) + 0x60,
+	})
+	if lang == "und" || code < 0x400 || code == 0x7fff {
+		lang = ""
+	}
+
+	return scaleDuration(duration, timescale), lang
+}
+
+// 获取音频样本描述中的声道数
+func mp4Channels(entry []byte) int {
+	if len(entry) < 18 {
+		return 0
+	}
+
+	// QuickTime 版本 2 的声道数位于扩展字段
+	if be(entry[8:10]) == 2 && len(entry) >= 44 {
+		return int(be(entry[40:44]))
+	}
+
+	return int(be(entry[16:18]))
+}
@@ -0,0 +1,114 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+// 生成 box
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := join(payload...)
+	return join(u32(uint32(8+len(body))), []byte(typ), body)
+}
+
+// 生成 mvhd，version 为 1 时使用 64 位时间
+func mp4Mvhd(version byte, timescale uint32, duration uint64) []byte {
+	if version == 1 {
+		return mp4Box("mvhd", pad(join([]byte{1, 0, 0, 0}, u64(0), u64(0), u32(timescale), u64(duration)), 112))
+	}
+	return mp4Box("mvhd", pad(join([]byte{0, 0, 0, 0}, u32(0), u32(0), u32(timescale), u32(uint32(duration))), 100))
+}
+
+// 生成 tkhd，尺寸为 16.16 定点数
+func mp4Tkhd(version byte, width, height int) []byte {
+	size := 84
+	if version == 1 {
+		size = 96
+	}
+	b := pad([]byte{version, 0, 0, 7}, size-8)
+	return mp4Box("tkhd", b, u32(uint32(width)<<16), u32(uint32(height)<<16))
+}
+
+// 生成 mdhd，语言为 3 个小写字母
+func mp4Mdhd(version byte, timescale uint32, duration uint64, lang string) []byte {
+	code := uint16(lang[0]-0x60)<<10 | uint16(lang[1]-0x60)<<5 | uint16(lang[2]-0x60)
+	if version == 1 {
+		return mp4Box("mdhd", []byte{1, 0, 0, 0}, u64(0), u64(0), u32(timescale), u64(duration), u16(code), u16(0))
+	}
+	return mp4Box("mdhd", []byte{0, 0, 0, 0}, u32(0), u32(0), u32(timescale), u32(uint32(duration)), u16(code), u16(0))
+}
+
+// 生成轨道
+func mp4Trak(tkhd, mdhd []byte, handler string, entry []byte) []byte {
+	hdlr := mp4Box("hdlr", u32(0), u32(0), []byte(handler), make([]byte, 12), []byte{0})
+	stsd := mp4Box("stsd", u32(0), u32(1), entry)
+	stbl := mp4Box("stbl", stsd)
+	return mp4Box("trak", tkhd, mp4Box("mdia", mdhd, hdlr, mp4Box("minf", stbl)))
+}
+
+// 生成视频样本描述
+func mp4VideoEntry(format string, width, height int) []byte {
+	return mp4Box(format, pad(join(make([]byte, 24), u16(uint16(width)), u16(uint16(height))), 78))
+}
+
+// 生成音频样本描述
+func mp4AudioEntry(format string, channels int) []byte {
+	return mp4Box(format, make([]byte, 16), u16(uint16(channels)), u16(16), make([]byte, 8))
+}
+
+// 生成文件，moov 位于 mdat 之后
+func mp4File(moov ...[]byte) []byte {
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(512), []byte("isomavc1"))
+	mdat := mp4Box("mdat", make([]byte, 1024))
+	return join(ftyp, mdat, mp4Box("moov", moov...))
+}
+
+func TestProbeMP4(t *testing.T) {
+	runProbeTests(t, []probeTest{
+		{
+			name: "v0",
+			fixture: mp4File(
+				mp4Mvhd(0, 1000, 5400000),
+				mp4Trak(mp4Tkhd(0, 1920, 1080), mp4Mdhd(0, 90000, 486000000, "und"), "vide", mp4VideoEntry("avc1", 1920, 1080)),
+				mp4Trak(mp4Tkhd(0, 0, 0), mp4Mdhd(0, 48000, 259200000, "jpn"), "soun", mp4AudioEntry("mp4a", 2)),
+			),
+			want: &Info{
+				Container: ContainerMP4,
+				Duration:  90 * time.Minute,
+				Video:     []VideoStream{{Codec: "h264", Width: 1920, Height: 1080}},
+				Audio:     []AudioStream{{Codec: "aac", Channels: 2, Language: "jpn"}},
+			},
+			resolution: "1080p",
+		},
+		{
+			// 64 位时长超出 32 位范围
+			name: "v1",
+			fixture: mp4File(
+				mp4Mvhd(1, 1000000, 7200000000),
+				mp4Trak(mp4Tkhd(1, 3840, 2160), mp4Mdhd(1, 1000000, 7200000000, "und"), "vide", mp4VideoEntry("hvc1", 3840, 2160)),
+				mp4Trak(mp4Tkhd(1, 0, 0), mp4Mdhd(1, 48000, 345600000, "eng"), "soun", mp4AudioEntry("ac-3", 6)),
+			),
+			want: &Info{
+				Container: ContainerMP4,
+				Duration:  2 * time.Hour,
+				Video:     []VideoStream{{Codec: "hevc", Width: 3840, Height: 2160}},
+				Audio:     []AudioStream{{Codec: "ac3", Channels: 6, Language: "eng"}},
+			},
+			resolution: "2160p",
+		},
+		{
+			// 全局时长未知时使用轨道时长，显示尺寸缺失时使用样本描述中的尺寸
+			name: "fallback",
+			fixture: mp4File(
+				mp4Mvhd(0, 1000, 0xffffffff),
+				mp4Trak(mp4Tkhd(0, 0, 0), mp4Mdhd(0, 30000, 18000000, "und"), "vide", mp4VideoEntry("avc1", 1280, 720)),
+			),
+			want: &Info{
+				Container: ContainerMP4,
+				Duration:  10 * time.Minute,
+				Video:     []VideoStream{{Codec: "h264", Width: 1280, Height: 720}},
+			},
+			resolution: "720p",
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrUnsupported 不支持的容器格式
+var ErrUnsupported = errors.New("不支持的视频格式")
+
+// 容器格式
+const (
+	ContainerMP4 = "mp4" // MP4/MOV
+	ContainerMKV = "mkv" // Matroska/WebM
+	ContainerTS  = "ts"  // MPEG-TS/M2TS
+)
+
+// Info 视频文件信息
+type Info struct {
+	Container string        // 容器格式，参见常量定义
+	Duration  time.Duration // 时长
+	Video     []VideoStream // 视频流
+	Audio     []AudioStream // 音频流
+}
+
+// VideoStream 视频流信息
+type VideoStream struct {
+	Codec  string // 编码，如 h264、hevc
+	Width  int    // 宽度
+	Height int    // 高度
+}
+
+// AudioStream 音频流信息
+type AudioStream struct {
+	Codec    string // 编码，如 aac、ac3
+	Channels int    // 声道数，未知时为 0
+	Language string // 语言，ISO 639-2 格式，未知时为空
+}
+
+// File 读取视频文件信息，不支持的格式返回 ErrUnsupported。
+//
+// file 字符串参数，传入视频文件路径。
+func File(file string) (*Info, error) {
+	// 打开文件
+	f, err := os.Open(file)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 获取大小
+	stat, err := f.Stat()
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	return Probe(f, stat.Size())
+}
+
+// Probe 根据文件头部判断容器格式并读取视频信息，不支持的格式返回 ErrUnsupported。
+//
+// r ReaderAt接口，传入视频内容，
+// size 整数参数，传入视频大小。
+func Probe(r io.ReaderAt, size int64) (*Info, error) {
+	// 读取文件头
+	head := make([]byte, 200)
+	n, err := r.ReadAt(head, 0)
+	// 检查
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	// 判断格式
+	switch {
+	case isMP4(head):
+		return probeMP4(r, size)
+	case bytes.HasPrefix(head, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return probeMKV(r, size)
+	case tsPacketSize(head) > 0:
+		return probeTS(r, size, tsPacketSize(head))
+	}
+
+	return nil, ErrUnsupported
+}
+
+// Resolution 获取第一个视频流的分辨率标识，如 720p、1080p、2160p，没有视频流时返回空
+func (i *Info) Resolution() string {
+	// 没有视频流
+	if len(i.Video) == 0 {
+		return ""
+	}
+
+	// 按宽度或高度判断，兼容宽银幕及竖屏视频
+	w, h := i.Video[0].Width, i.Video[0].Height
+	switch {
+	case w >= 7680 || h >= 4320:
+		return "4320p"
+	case w >= 3840 || h >= 2160:
+		return "2160p"
+	case w >= 2560 || h >= 1440:
+		return "1440p"
+	case w >= 1920 || h >= 1080:
+		return "1080p"
+	case w >= 1280 || h >= 720:
+		return "720p"
+	case h > 0:
+		return fmt.Sprintf("%dp", h)
+	}
+
+	return ""
+}
+
+// 读取指定位置的内容，超出文件大小时截断
+func readAt(r io.ReaderAt, off int64, n int, size int64) ([]byte, error) {
+	// 截断
+	if off+int64(n) > size {
+		n = int(size - off)
+	}
+	if n <= 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n)
+	_, err := r.ReadAt(b, off)
+	// 检查
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// 大端序整数
+func be(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v
+}
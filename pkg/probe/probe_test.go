@@ -0,0 +1,84 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// 测试用例，fixture 为测试中按格式规范构造的合成文件内容，并非真实视频
+type probeTest struct {
+	name       string
+	fixture    []byte
+	want       *Info
+	resolution string
+}
+
+// 读取合成文件并比较结果
+func runProbeTests(t *testing.T, tests []probeTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Probe(bytes.NewReader(tt.fixture), int64(len(tt.fixture)))
+			if err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+			if got.Container != tt.want.Container {
+				t.Errorf("Container = %q, want %q", got.Container, tt.want.Container)
+			}
+			if got.Duration != tt.want.Duration {
+				t.Errorf("Duration = %v, want %v", got.Duration, tt.want.Duration)
+			}
+			if !reflect.DeepEqual(got.Video, tt.want.Video) {
+				t.Errorf("Video = %+v, want %+v", got.Video, tt.want.Video)
+			}
+			if !reflect.DeepEqual(got.Audio, tt.want.Audio) {
+				t.Errorf("Audio = %+v, want %+v", got.Audio, tt.want.Audio)
+			}
+			if r := got.Resolution(); r != tt.resolution {
+				t.Errorf("Resolution() = %q, want %q", r, tt.resolution)
+			}
+		})
+	}
+}
+
+// 大端序 16 位整数
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+// 大端序 32 位整数
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// 大端序 64 位整数
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// 拼接字节集
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// 补零到指定长度
+func pad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	return append(b, make([]byte, n-len(b))...)
+}
+
+func TestProbeUnsupported(t *testing.T) {
+	b := []byte("RIFF\x00\x00\x00\x00AVI LIST")
+	if _, err := Probe(bytes.NewReader(b), int64(len(b))); err != ErrUnsupported {
+		t.Errorf("Probe() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,415 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// 读取文件开头及结尾查找节目信息及 PCR 的大小
+const tsScanSize = 8 << 20
+
+// 每个流最多缓存的数据大小，用于解析 SPS 及音频帧头
+const tsStreamSize = 256 << 10
+
+// PCR 最大值，33 位基准乘以 300
+const pcrWrap = (1 << 33) * 300
+
+// TS 流类型
+var tsStreamTypes = map[byte]struct {
+	video bool
+	codec string
+}{
+	0x01: {true, "mpeg1video"},
+	0x02: {true, "mpeg2video"},
+	0x10: {true, "mpeg4"},
+	0x1b: {true, "h264"},
+	0x24: {true, "hevc"},
+	0xea: {true, "vc1"},
+	0x03: {false, "mp2"},
+	0x04: {false, "mp3"},
+	0x0f: {false, "aac"},
+	0x11: {false, "aac"},
+	0x80: {false, "pcm"},
+	0x81: {false, "ac3"},
+	0x82: {false, "dts"},
+	0x83: {false, "truehd"},
+	0x84: {false, "eac3"},
+	0x85: {false, "dts"},
+	0x86: {false, "dts"},
+	0x87: {false, "eac3"},
+}
+
+// TS 流信息
+type tsStream struct {
+	pid   int
+	video bool
+	codec string
+	lang  string
+	// 流开头的数据
+	data []byte
+	// 是否已开始缓存，需从 PES 开头开始
+	started bool
+}
+
+// 判断 TS 包大小，188 为 MPEG-TS，192 为带时间码的 M2TS，不是 TS 时返回 0
+func tsPacketSize(head []byte) int {
+	for _, size := range []int{188, 192} {
+		// 同步字节位置
+		sync := size - 188
+		if len(head) > size+sync && head[sync] == 0x47 && head[size+sync] == 0x47 {
+			return size
+		}
+	}
+
+	return 0
+}
+
+// 读取 MPEG-TS 信息
+func probeTS(r io.ReaderAt, size int64, packetSize int) (*Info, error) {
+	// 读取文件开头
+	head, err := readAt(r, 0, tsScanSize, size)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		// 节目表 PID
+		pmtPID = -1
+		// PCR 所在 PID
+		pcrPID = -1
+		// 第一个 PCR
+		firstPCR int64 = -1
+		// 各流信息
+		streams []*tsStream
+		byPID   = make(map[int]*tsStream)
+	)
+
+	eachPacket(head, packetSize, func(pid int, start bool, pcr int64, payload []byte) {
+		switch {
+		case pid == 0 && start && pmtPID < 0:
+			// 节目关联表
+			pmtPID = parsePAT(payload)
+		case pid == pmtPID && start && streams == nil:
+			// 节目映射表
+			pcrPID, streams = parsePMT(payload)
+			for _, s := range streams {
+				byPID[s.pid] = s
+			}
+		}
+
+		// 第一个 PCR
+		if pcr >= 0 && pid == pcrPID && firstPCR < 0 {
+			firstPCR = pcr
+		}
+
+		// 缓存流开头的数据
+		if s, ok := byPID[pid]; ok && len(s.data) < tsStreamSize {
+			if start {
+				s.started = true
+				payload = pesPayload(payload)
+			}
+			if s.started {
+				s.data = append(s.data, payload...)
+			}
+		}
+	})
+	if streams == nil {
+		return nil, fmt.Errorf("ts 缺少节目信息")
+	}
+
+	info := &Info{Container: ContainerTS}
+
+	// 读取文件结尾查找最后一个 PCR
+	tailOff := size - tsScanSize
+	if tailOff < 0 {
+		tailOff = 0
+	}
+	tail, err := readAt(r, tailOff, int(size-tailOff), size)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+	lastPCR := int64(-1)
+	eachPacket(tail[tsAlign(tail, packetSize):], packetSize, func(pid int, start bool, pcr int64, payload []byte) {
+		if pcr >= 0 && pid == pcrPID {
+			lastPCR = pcr
+		}
+	})
+	if firstPCR >= 0 && lastPCR >= 0 {
+		if lastPCR < firstPCR {
+			lastPCR += pcrWrap
+		}
+		// PCR 时钟频率为 27MHz
+		info.Duration = time.Duration((lastPCR - firstPCR) * 1000 / 27)
+	}
+
+	// 各流信息
+	for _, s := range streams {
+		if s.video {
+			v := VideoStream{Codec: s.codec}
+			v.Width, v.Height = videoSize(s.codec, s.data)
+			info.Video = append(info.Video, v)
+		} else {
+			info.Audio = append(info.Audio, AudioStream{Codec: s.codec, Channels: audioChannels(s.codec, s.data), Language: s.lang})
+		}
+	}
+
+	return info, nil
+}
+
+// 查找第一个完整 TS 包的位置
+func tsAlign(b []byte, packetSize int) int {
+	sync := packetSize - 188
+	for i := 0; i+2*packetSize+sync < len(b) && i < packetSize; i++ {
+		if b[i+sync] == 0x47 && b[i+packetSize+sync] == 0x47 && b[i+2*packetSize+sync] == 0x47 {
+			return i
+		}
+	}
+
+	return len(b)
+}
+
+// 遍历 TS 包，pcr 为 -1 时表示没有 PCR
+func eachPacket(b []byte, packetSize int, fn func(pid int, start bool, pcr int64, payload []byte)) {
+	sync := packetSize - 188
+	for ; len(b) >= packetSize; b = b[packetSize:] {
+		p := b[sync:packetSize]
+		if p[0] != 0x47 {
+			continue
+		}
+
+		pid := int(p[1]&0x1f)<<8 | int(p[2])
+		start := p[1]&0x40 != 0
+		control := p[3] >> 4 & 0x03
+
+		// 适配域
+		payload := p[4:]
+		pcr := int64(-1)
+		if control&0x02 != 0 {
+			length := int(payload[0])
+			if length > len(payload)-1 {
+				continue
+			}
+			field := payload[1 : 1+length]
+			if length >= 7 && field[0]&0x10 != 0 {
+				base := int64(be(field[1:5]))<<1 | int64(field[5]>>7)
+				ext := int64(field[5]&0x01)<<8 | int64(field[6])
+				pcr = base*300 + ext
+			}
+			payload = payload[1+length:]
+		}
+		if control&0x01 == 0 {
+			payload = nil
+		}
+
+		fn(pid, start, pcr, payload)
+	}
+}
+
+// 获取 PSI 表内容，跳过指针域，返回表 ID 之后的数据
+func psiSection(payload []byte, table byte) []byte {
+	if len(payload) == 0 || int(payload[0])+1 >= len(payload) {
+		return nil
+	}
+	b := payload[1+int(payload[0]):]
+	if len(b) < 8 || b[0] != table {
+		return nil
+	}
+
+	// 表长度，不含 CRC
+	length := int(b[1]&0x0f)<<8 | int(b[2])
+	if length < 9 || 3+length > len(b) {
+		return nil
+	}
+
+	return b[3 : 3+length-4]
+}
+
+// 解析节目关联表，返回第一个节目的节目映射表 PID
+func parsePAT(payload []byte) int {
+	b := psiSection(payload, 0x00)
+	if b == nil {
+		return -1
+	}
+
+	// 跳过传输流 ID、版本号及分段号
+	for b = b[5:]; len(b) >= 4; b = b[4:] {
+		// 节目 0 为网络信息表
+		if be(b[0:2]) != 0 {
+			return int(b[2]&0x1f)<<8 | int(b[3])
+		}
+	}
+
+	return -1
+}
+
+// 解析节目映射表，返回 PCR 所在 PID 及各流信息
+func parsePMT(payload []byte) (int, []*tsStream) {
+	b := psiSection(payload, 0x02)
+	if b == nil || len(b) < 9 {
+		return -1, nil
+	}
+
+	pcrPID := int(b[5]&0x1f)<<8 | int(b[6])
+	infoLength := int(b[7]&0x0f)<<8 | int(b[8])
+	if 9+infoLength > len(b) {
+		return pcrPID, nil
+	}
+
+	streams := make([]*tsStream, 0)
+	for b = b[9+infoLength:]; len(b) >= 5; {
+		typ := b[0]
+		pid := int(b[1]&0x1f)<<8 | int(b[2])
+		length := int(b[3]&0x0f)<<8 | int(b[4])
+		if 5+length > len(b) {
+			break
+		}
+		descriptors := b[5 : 5+length]
+		b = b[5+length:]
+
+		s := &tsStream{pid: pid}
+		if t, ok := tsStreamTypes[typ]; ok {
+			s.video, s.codec = t.video, t.codec
+		}
+
+		// 描述符
+		for d := descriptors; len(d) >= 2 && 2+int(d[1]) <= len(d); d = d[2+int(d[1]):] {
+			data := d[2 : 2+int(d[1])]
+			switch d[0] {
+			case 0x0a:
+				// 语言
+				if len(data) >= 3 && string(data[:3]) != "und" {
+					s.lang = string(data[:3])
+				}
+			case 0x6a:
+				s.codec = "ac3"
+			case 0x7a:
+				s.codec = "eac3"
+			case 0x7b:
+				s.codec = "dts"
+			case 0x05:
+				// 注册描述符
+				if len(data) >= 4 {
+					switch string(data[:4]) {
+					case "AC-3":
+						s.codec = "ac3"
+					case "EAC3":
+						s.codec = "eac3"
+					case "HEVC":
+						s.video, s.codec = true, "hevc"
+					}
+				}
+			}
+		}
+
+		// 忽略字幕、数据等其他流
+		if s.codec != "" {
+			streams = append(streams, s)
+		}
+	}
+
+	return pcrPID, streams
+}
+
+// 去除 PES 头，返回基本流数据
+func pesPayload(payload []byte) []byte {
+	if len(payload) < 9 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+		return nil
+	}
+	if 9+int(payload[8]) > len(payload) {
+		return nil
+	}
+
+	return payload[9+int(payload[8]):]
+}
+
+// 从视频流开头获取分辨率
+func videoSize(codec string, data []byte) (int, int) {
+	switch codec {
+	case "h264":
+		for _, nal := range splitNAL(data) {
+			if len(nal) > 1 && nal[0]&0x1f == 7 {
+				return parseAVCSPS(nal[1:])
+			}
+		}
+	case "hevc":
+		for _, nal := range splitNAL(data) {
+			if len(nal) > 2 && nal[0]>>1&0x3f == 33 {
+				return parseHEVCSPS(nal[2:])
+			}
+		}
+	case "mpeg1video", "mpeg2video":
+		// 序列头
+		for i := 0; i+7 < len(data); i++ {
+			if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 && data[i+3] == 0xb3 {
+				return int(data[i+4])<<4 | int(data[i+5]>>4), int(data[i+5]&0x0f)<<8 | int(data[i+6])
+			}
+		}
+	}
+
+	return 0, 0
+}
+
+// 从音频流开头获取声道数
+func audioChannels(codec string, data []byte) int {
+	switch codec {
+	case "aac":
+		// ADTS 帧头
+		for i := 0; i+4 < len(data); i++ {
+			if data[i] == 0xff && data[i+1]&0xf6 == 0xf0 {
+				channels := int(data[i+2]&0x01)<<2 | int(data[i+3]>>6)
+				// 7 为 7.1 声道
+				if channels == 7 {
+					return 8
+				}
+				return channels
+			}
+		}
+	case "ac3", "eac3":
+		for i := 0; i+7 < len(data); i++ {
+			if data[i] == 0x0b && data[i+1] == 0x77 {
+				return ac3Channels(data[i:])
+			}
+		}
+	case "mp2", "mp3":
+		for i := 0; i+4 < len(data); i++ {
+			if data[i] == 0xff && data[i+1]&0xe0 == 0xe0 {
+				// 声道模式 3 为单声道
+				if data[i+3]>>6 == 3 {
+					return 1
+				}
+				return 2
+			}
+		}
+	}
+
+	return 0
+}
+
+// 解析 AC-3 及 E-AC-3 帧头中的声道数
+func ac3Channels(b []byte) int {
+	// 各声道模式的声道数
+	modes := []int{2, 1, 2, 3, 3, 4, 4, 5}
+
+	// E-AC-3
+	if b[5]>>3 > 10 {
+		return modes[b[4]>>1&0x07] + int(b[4]&0x01)
+	}
+
+	// AC-3，声道模式之后的可选字段决定低音声道位置
+	r := &bitReader{b: b[6:]}
+	acmod := int(r.u(3))
+	if acmod&0x01 != 0 && acmod != 1 {
+		r.u(2)
+	}
+	if acmod&0x04 != 0 {
+		r.u(2)
+	}
+	if acmod == 2 {
+		r.u(2)
+	}
+
+	return modes[acmod] + int(r.u(1))
+}
@@ -0,0 +1,173 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试用 PID
+const (
+	tsPMTPID   = 0x100
+	tsVideoPID = 0x101
+	tsAudioPID = 0x102
+)
+
+// 位写入器，用于生成 SPS
+type bitWriter struct {
+	b []byte
+	n int
+}
+
+// 写入 n 位无符号整数
+func (w *bitWriter) u(n int, v uint64) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		w.b[len(w.b)-1] |= byte(v>>uint(i)&0x01) << (7 - uint(w.n%8))
+		w.n++
+	}
+}
+
+// 写入无符号指数哥伦布编码
+func (w *bitWriter) ue(v uint64) {
+	bits := 0
+	for x := v + 1; x > 1; x >>= 1 {
+		bits++
+	}
+	w.u(bits, 0)
+	w.u(bits+1, v+1)
+}
+
+// 生成 Baseline 档次的 H.264 SPS，含 NAL 头
+func avcSPS(width, height int) []byte {
+	w := &bitWriter{}
+	// NAL 头
+	w.u(8, 0x67)
+	// 档次、约束标识、级别及 SPS ID
+	w.u(8, 66)
+	w.u(16, 40)
+	w.ue(0)
+	// 帧序号、图像顺序类型 2、参考帧数量
+	w.ue(0)
+	w.ue(2)
+	w.ue(1)
+	w.u(1, 0)
+	// 宏块数量，高度向上取整
+	mbw, mbh := (width+15)/16, (height+15)/16
+	w.ue(uint64(mbw - 1))
+	w.ue(uint64(mbh - 1))
+	w.u(1, 1)
+	w.u(1, 1)
+	// 裁剪，4:2:0 单位为 2
+	crop := (mbh*16 - height) / 2
+	w.u(1, 1)
+	w.ue(0)
+	w.ue(uint64((mbw*16 - width) / 2))
+	w.ue(0)
+	w.ue(uint64(crop))
+	// 无 VUI 及结束位
+	w.u(1, 0)
+	w.u(1, 1)
+
+	return w.b
+}
+
+// 生成 ADTS 帧头
+func adtsHeader(channels int) []byte {
+	return []byte{0xff, 0xf1, 0x50 | byte(channels>>2), byte(channels&0x03) << 6, 0x00, 0x1f, 0xfc}
+}
+
+// 生成 PSI 分段，CRC 不参与解析，使用 0 填充
+func tsSection(table byte, body []byte) []byte {
+	length := len(body) + 4
+	return join([]byte{0x00, table, 0xb0 | byte(length>>8), byte(length)}, body, u32(0))
+}
+
+// 生成 PES 包头
+func tsPES(streamID byte, data []byte) []byte {
+	return join([]byte{0x00, 0x00, 0x01, streamID, 0x00, 0x00, 0x80, 0x00, 0x00}, data)
+}
+
+// 生成 TS 包，pcr 小于 0 时不写入，不足部分使用 0xff 填充
+func tsPacket(packetSize, pid int, start bool, pcr int64, payload []byte) []byte {
+	head := []byte{0x47, byte(pid >> 8 & 0x1f), byte(pid), 0x10}
+	if start {
+		head[1] |= 0x40
+	}
+	if pcr >= 0 {
+		base, ext := pcr/300, pcr%300
+		head[3] |= 0x20
+		head = append(head, 7, 0x10)
+		head = append(head, u32(uint32(base>>1))...)
+		head = append(head, byte(base&0x01)<<7|0x7e|byte(ext>>8), byte(ext))
+	}
+	if payload == nil {
+		head[3] &^= 0x10
+	}
+
+	p := join(head, payload)
+	for len(p) < 188 {
+		p = append(p, 0xff)
+	}
+
+	// M2TS 包头为 4 字节时间码
+	return join(make([]byte, packetSize-188), p)
+}
+
+// 生成文件，firstPCR 及 lastPCR 分别写入视频流的第一个及最后一个包
+func tsFile(packetSize int, firstPCR, lastPCR int64) []byte {
+	// 节目关联表，节目 1
+	pat := tsSection(0x00, join(u16(1), []byte{0xc1, 0x00, 0x00}, u16(1), u16(0xe000|tsPMTPID)))
+	// 节目映射表，H.264 视频及带语言描述的 AAC 音频
+	pmt := tsSection(0x02, join(
+		u16(1), []byte{0xc1, 0x00, 0x00}, u16(0xe000|tsVideoPID), u16(0xf000),
+		[]byte{0x1b}, u16(0xe000|tsVideoPID), u16(0xf000),
+		[]byte{0x0f}, u16(0xe000|tsAudioPID), u16(0xf006), []byte{0x0a, 0x04}, []byte("jpn"), []byte{0x00},
+	))
+
+	video := tsPES(0xe0, join([]byte{0x00, 0x00, 0x00, 0x01}, avcSPS(1920, 1080), []byte{0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x38, 0x80}))
+	audio := tsPES(0xc0, adtsHeader(6))
+
+	packets := [][]byte{
+		tsPacket(packetSize, 0, true, -1, pat),
+		tsPacket(packetSize, tsPMTPID, true, -1, pmt),
+		tsPacket(packetSize, tsVideoPID, true, firstPCR, video),
+		tsPacket(packetSize, tsAudioPID, true, -1, audio),
+	}
+	for i := 0; i < 8; i++ {
+		packets = append(packets, tsPacket(packetSize, 0x1fff, false, -1, []byte{}))
+	}
+	packets = append(packets, tsPacket(packetSize, tsVideoPID, false, lastPCR, nil))
+
+	return join(packets...)
+}
+
+func TestProbeTS(t *testing.T) {
+	// PCR 时钟频率为 27MHz
+	second := int64(27000000)
+	want := []VideoStream{{Codec: "h264", Width: 1920, Height: 1080}}
+	audio := []AudioStream{{Codec: "aac", Channels: 6, Language: "jpn"}}
+
+	runProbeTests(t, []probeTest{
+		{
+			name:       "mpegts",
+			fixture:    tsFile(188, 10*second, 3610*second),
+			want:       &Info{Container: ContainerTS, Duration: time.Hour, Video: want, Audio: audio},
+			resolution: "1080p",
+		},
+		{
+			name:       "m2ts",
+			fixture:    tsFile(192, 0, 90*second),
+			want:       &Info{Container: ContainerTS, Duration: 90 * time.Second, Video: want, Audio: audio},
+			resolution: "1080p",
+		},
+		{
+			// PCR 在文件中回绕
+			name:       "wrap",
+			fixture:    tsFile(188, pcrWrap-5*second, 5*second),
+			want:       &Info{Container: ContainerTS, Duration: 10 * time.Second, Video: want, Audio: audio},
+			resolution: "1080p",
+		},
+	})
+}
@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -15,9 +16,21 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:       "CaribBeanCom",
+	Priority:   10,
+	Pattern:    `^\d{6}-\d{3}$`,
+	Uncensored: true,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewCaribBeanComScraper(cfg.Base.Proxy)
+	},
+})
+
 // CaribBeanComScraper 加勒比网站刮削器
 type CaribBeanComScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	number string            // 最终番号
 	root   *goquery.Document // 根节点
@@ -32,6 +45,13 @@ func NewCaribBeanComScraper(proxy string) *CaribBeanComScraper {
 
 // Fetch 刮削
 func (s *CaribBeanComScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *CaribBeanComScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 
@@ -39,7 +59,7 @@ func (s *CaribBeanComScraper) Fetch(code string) error {
 	uri := fmt.Sprintf("https://www.caribbeancom.com/moviepages/%s/index.html", code)
 
 	// 打开远程连接
-	data, err := util.GetResult(uri, s.Proxy, nil)
+	data, err := util.GetResultContext(s.ctx, uri, s.Proxy, nil)
 	// 检查
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -10,9 +11,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:     "DMM",
+	Priority: 70,
+	Pattern:  `[a-zA-Z]{2,5}[-|\s\S][0-9]{3,4}`,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewDMMScraper(cfg.Base.Proxy)
+	},
+})
+
 // DMMScraper dmm网站刮削器
 type DMMScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	code   string            // 临时番号
 	number string            // 最终番号
@@ -28,6 +40,13 @@ func NewDMMScraper(proxy string) *DMMScraper {
 
 // Fetch 刮削
 func (s *DMMScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *DMMScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 大写
 	code = strings.ToUpper(code)
 	// 设置番号
@@ -48,10 +67,17 @@ func (s *DMMScraper) Fetch(code string) error {
 // code 字符串参数，传入番号，
 // proxy 字符串参数，传入代理信息
 func GetDmmIntro(code, proxy string) string {
+	return GetDmmIntroContext(context.Background(), code, proxy)
+}
+
+// GetDmmIntroContext 携带上下文从dmm网站中获取影片简介，其余参数同 GetDmmIntro
+//
+// ctx 上下文参数，传入请求所使用的上下文。
+func GetDmmIntroContext(ctx context.Context, code, proxy string) string {
 	// 实例化对象
 	s := NewDMMScraper(proxy)
 	// 获取数据
-	err := s.Fetch(code)
+	err := s.FetchContext(ctx, code)
 	// 检查
 	if err != nil {
 		return ""
@@ -84,11 +110,11 @@ func (s *DMMScraper) getRoot() error {
 	// 循环
 	for _, uri := range uris {
 		// 打开连接
-		root, err = util.GetRoot(fmt.Sprintf(uri, s.code), s.Proxy, cookies)
+		root, err = util.GetRootContext(s.ctx, fmt.Sprintf(uri, s.code), s.Proxy, cookies)
 		// 检查
 		if err == nil {
 			// 设置页面地址
-			s.uri = uri
+			s.uri = fmt.Sprintf(uri, s.code)
 			// 设置根节点
 			s.root = root
 
@@ -130,6 +156,23 @@ func (s *DMMScraper) GetDirector() string {
 	return director
 }
 
+// GetWriter 获取编剧
+func (s *DMMScraper) GetWriter() string {
+	// 获取编剧
+	writer := s.root.Find(`td:contains("脚本：")`).Next().Find("a").Text()
+	// 如果没有
+	if writer == "" {
+		writer = s.root.Find(`td:contains("脚本：")`).Next().Text()
+	}
+	// 没有编剧时显示为 ----
+	writer = strings.TrimSpace(writer)
+	if strings.Trim(writer, "-") == "" {
+		return ""
+	}
+
+	return writer
+}
+
 // GetRelease 发行时间
 func (s *DMMScraper) GetRelease() string {
 	// 获取发行时间
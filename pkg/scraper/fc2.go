@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"golang.org/x/net/html"
@@ -26,13 +27,25 @@ const (
 	exprTags        = `//a[@class='tag tagTag']/text()`
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:       "FC2",
+	Priority:   50,
+	Pattern:    `^fc2-(ppv-)?[0-9]{6,7}`,
+	Uncensored: true,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewFC2Scraper(cfg.Base.Proxy)
+	},
+})
+
 // FC2Scraper fc2网站刮削器
 type FC2Scraper struct {
-	Proxy   string     // 代理设置
-	uri     string     // 页面地址
-	code    string     // 临时番号
-	number  string     // 最终番号
-	fc2Root *html.Node // fc2根节点
+	Proxy   string          // 代理设置
+	ctx     context.Context // 上下文
+	uri     string          // 页面地址
+	code    string          // 临时番号
+	number  string          // 最终番号
+	fc2Root *html.Node      // fc2根节点
 }
 
 // fc2标签json结构
@@ -54,6 +67,13 @@ func NewFC2Scraper(proxy string) *FC2Scraper {
 
 // Fetch 刮削
 func (s *FC2Scraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *FC2Scraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 	// 过滤番号
@@ -63,9 +83,9 @@ func (s *FC2Scraper) Fetch(code string) error {
 	// 组合fc2地址
 	fc2uri := fmt.Sprintf("https://adult.contents.fc2.com/article/%s/", s.code)
 
-	data, status, err := util.MakeRequest("GET", fc2uri, s.Proxy, nil, nil, nil)
+	data, status, err := util.MakeRequestContext(s.ctx, "GET", fc2uri, s.Proxy, nil, nil, nil)
 	if err != nil || status >= http.StatusBadRequest {
-		return fmt.Errorf("%s [fetch]: status: %d", status)
+		return fmt.Errorf("%s [fetch]: status: %d", fc2uri, status)
 	}
 
 	fc2Root, err := htmlquery.Parse(bytes.NewReader(data))
@@ -134,7 +154,7 @@ func (s *FC2Scraper) GetTags() []string {
 	uri := fmt.Sprintf("https://adult.contents.fc2.com/api/v4/article/%s/tag?", s.code)
 
 	// 读取远程数据
-	data, err := util.GetResult(uri, s.Proxy, nil)
+	data, err := util.GetResultContext(s.ctx, uri, s.Proxy, nil)
 	// 检查
 	if err != nil {
 		return nil
@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,9 +14,21 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:       "Heydouga",
+	Priority:   40,
+	Pattern:    `([0-9]{4}).+?([0-9]{3,4})$`,
+	Uncensored: true,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewHeydougaScraper(cfg.Base.Proxy)
+	},
+})
+
 // HeydougaScraper heydouga网站刮削器
 type HeydougaScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	data   string            // 页面数据
 	code   string            // 临时番号
@@ -44,6 +57,13 @@ func NewHeydougaScraper(proxy string) *HeydougaScraper {
 
 // Fetch 刮削
 func (s *HeydougaScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *HeydougaScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置临时番号
 	s.code = code
 	// 转换大写
@@ -70,7 +90,7 @@ func (s *HeydougaScraper) Fetch(code string) error {
 	// 组合地址
 	uri := fmt.Sprintf("https://www.heydouga.com/moviepages/%s/%s/index.html", s.code1, s.code2)
 	// 打开连接
-	data, status, err := util.MakeRequest("GET", uri, s.Proxy, nil, nil, nil)
+	data, status, err := util.MakeRequestContext(s.ctx, "GET", uri, s.Proxy, nil, nil, nil)
 	// 检查
 	if err != nil || status >= http.StatusBadRequest {
 		// 设置番号前后缀
@@ -79,7 +99,7 @@ func (s *HeydougaScraper) Fetch(code string) error {
 		// 重新组合地址
 		uri = fmt.Sprintf("https://www.heydouga.com/moviepages/%s/%s/index.html", s.code1, s.code2)
 		// 打开链接
-		data, status, err = util.MakeRequest("GET", uri, s.Proxy, nil, nil, nil)
+		data, status, err = util.MakeRequestContext(s.ctx, "GET", uri, s.Proxy, nil, nil, nil)
 		// 检查
 		if err != nil || status >= http.StatusBadRequest {
 			return fmt.Errorf("%s [fetch]: 404 Not Found", uri)
@@ -154,7 +174,7 @@ func (s *HeydougaScraper) GetTags() []string {
 	// 组合路径
 	uri := fmt.Sprintf("https://www.heydouga.com/get_movie_tag_all_utf8/?movie_seq=%s", m)
 	// 获取数据
-	data, err := util.GetResult(uri, s.Proxy, nil)
+	data, err := util.GetResultContext(s.ctx, uri, s.Proxy, nil)
 	// 检查错误
 	if err != nil {
 		return nil
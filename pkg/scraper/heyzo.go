@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
@@ -13,9 +14,21 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:       "Heyzo",
+	Priority:   30,
+	Pattern:    `^heyzo-[0-9]{4}`,
+	Uncensored: true,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewHeyzoScraper(cfg.Base.Proxy)
+	},
+})
+
 // HeyzoScraper heyzo网站刮削器
 type HeyzoScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	code   string            // 临时番号
 	number string            // 最终番号
@@ -47,6 +60,13 @@ func NewHeyzoScraper(proxy string) *HeyzoScraper {
 
 // Fetch 刮削
 func (s *HeyzoScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *HeyzoScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 	// 番号正则
@@ -56,7 +76,7 @@ func (s *HeyzoScraper) Fetch(code string) error {
 	// 组合地址
 	uri := fmt.Sprintf("https://www.heyzo.com/moviepages/%s/index.html", s.code)
 	// 打开连接
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查
 	if err != nil {
 		return err
@@ -174,6 +194,25 @@ func (s *HeyzoScraper) GetActors() map[string]string {
 	return actors
 }
 
+// GetRating 获取评分，转换为满分 10 分
+func (s *HeyzoScraper) GetRating() (float64, int) {
+	// 评分
+	value, err := strconv.ParseFloat(s.json.AggregateRating.RatingValue, 64)
+	// 检查
+	if err != nil || value <= 0 {
+		return 0, 0
+	}
+	// 满分，默认为 5 分
+	best, err := strconv.ParseFloat(s.json.AggregateRating.BestRating, 64)
+	if err != nil || best <= 0 {
+		best = 5
+	}
+	// 评分人数
+	votes, _ := strconv.Atoi(s.json.AggregateRating.ReviewCount)
+
+	return value * 10 / best, votes
+}
+
 // GetURI 获取页面地址
 func (s *HeyzoScraper) GetURI() string {
 	return s.uri
@@ -7,6 +7,8 @@ scraper通过IScraper接口，对具体网站进行数据刮削，
 */
 package scraper
 
+import "context"
+
 // IScraper 刮削器接口
 type IScraper interface {
 	// Fetch 执行刮削，并返回刮削结果
@@ -41,3 +43,74 @@ type IScraper interface {
 	// GetActors 从刮削结果中获取影片演员
 	GetActors() map[string]string
 }
+
+// IRatingScraper 可获取评分的刮削器接口，
+// 并非所有网站都提供评分，因此独立于 IScraper。
+type IRatingScraper interface {
+	// GetRating 从刮削结果中获取影片评分，满分为 10 分，以及评分人数，没有评分时返回 0
+	GetRating() (float64, int)
+}
+
+// IWriterScraper 支持编剧信息的刮削器接口
+type IWriterScraper interface {
+	// GetWriter 从刮削结果中获取影片编剧，没有编剧时返回空字符串
+	GetWriter() string
+}
+
+// IContextScraper 支持上下文的刮削器接口，
+// 刮削过程及后续获取数据时的远程请求均可通过上下文取消或超时。
+type IContextScraper interface {
+	IScraper
+
+	// FetchContext 携带上下文执行刮削，并返回刮削结果
+	//
+	// ctx 上下文参数，传入刮削所使用的上下文，
+	// code 字符串参数，传入番号信息
+	FetchContext(ctx context.Context, code string) error
+}
+
+// GetRating 获取刮削对象的评分，
+// 若刮削对象不支持评分，则返回 0。
+//
+// s IScraper刮削接口，传入刮削对象。
+func GetRating(s IScraper) (float64, int) {
+	// 是否支持评分
+	if rs, ok := s.(IRatingScraper); ok {
+		return rs.GetRating()
+	}
+
+	return 0, 0
+}
+
+// GetWriter 获取刮削对象的编剧，
+// 若刮削对象不支持编剧，则返回空字符串。
+//
+// s IScraper刮削接口，传入刮削对象。
+func GetWriter(s IScraper) string {
+	// 是否支持编剧
+	if ws, ok := s.(IWriterScraper); ok {
+		return ws.GetWriter()
+	}
+
+	return ""
+}
+
+// FetchContext 携带上下文执行刮削，
+// 若刮削对象不支持上下文，则退化为普通刮削。
+//
+// ctx 上下文参数，传入刮削所使用的上下文，
+// s IScraper刮削接口，传入刮削对象，
+// code 字符串参数，传入番号信息。
+func FetchContext(ctx context.Context, s IScraper, code string) error {
+	// 是否支持上下文
+	if cs, ok := s.(IContextScraper); ok {
+		return cs.FetchContext(ctx, code)
+	}
+
+	// 上下文是否已结束
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Fetch(code)
+}
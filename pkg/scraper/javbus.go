@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -9,10 +10,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:     "JavBus",
+	Priority: 90,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewJavBusScraper(cfg.Site.JavBus, cfg.Base.Proxy)
+	},
+})
+
 // JavBusScraper javbus网站刮削器
 type JavBusScraper struct {
 	Site   string            // 免翻地址
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	number string            // 最终番号
 	root   *goquery.Document // 根节点
@@ -28,6 +39,13 @@ func NewJavBusScraper(site, proxy string) *JavBusScraper {
 
 // Fetch 刮削
 func (s *JavBusScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *JavBusScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 	// 获取信息
@@ -59,7 +77,7 @@ func (s *JavBusScraper) detail() error {
 	// 组合uri
 	uri := fmt.Sprintf("%s/%s", util.CheckDomainPrefix(s.Site), s.number)
 	// 获取节点
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查错误
 	if err != nil {
 		return err
@@ -85,7 +103,7 @@ func (s *JavBusScraper) GetTitle() string {
 
 // GetIntro 获取简介
 func (s *JavBusScraper) GetIntro() string {
-	return GetDmmIntro(s.number, s.Proxy)
+	return GetDmmIntroContext(s.ctx, s.number, s.Proxy)
 }
 
 // GetDirector 获取导演
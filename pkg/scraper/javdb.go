@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -9,10 +10,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:     "JavDB",
+	Priority: 80,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewJavDBScraper(cfg.Site.JavDB, cfg.Base.Proxy)
+	},
+})
+
 // JavDBScraper javdb网站刮削器
 type JavDBScraper struct {
 	Site   string            // 免翻地址
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	number string            // 最终番号
 	root   *goquery.Document // 根节点
@@ -28,6 +39,13 @@ func NewJavDBScraper(site, proxy string) *JavDBScraper {
 
 // Fetch 刮削
 func (s *JavDBScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *JavDBScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 	// 搜索
@@ -41,7 +59,7 @@ func (s *JavDBScraper) Fetch(code string) error {
 	uri := fmt.Sprintf("%s%s", util.CheckDomainPrefix(s.Site), id)
 
 	// 打开连接
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查错误
 	if err != nil {
 		return fmt.Errorf("%s [fetch]: %s", uri, err)
@@ -61,7 +79,7 @@ func (s *JavDBScraper) search() (string, error) {
 	uri := fmt.Sprintf("%s/search?q=%s&f=all", util.CheckDomainPrefix(s.Site), strings.ToUpper(s.number))
 
 	// 打开地址
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查错误
 	if err != nil {
 		return "", err
@@ -106,7 +124,7 @@ func (s *JavDBScraper) GetTitle() string {
 
 // GetIntro 获取简介
 func (s *JavDBScraper) GetIntro() string {
-	return GetDmmIntro(s.number, s.Proxy)
+	return GetDmmIntroContext(s.ctx, s.number, s.Proxy)
 }
 
 // GetDirector 获取导演
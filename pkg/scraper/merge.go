@@ -0,0 +1,300 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// 合并字段名称
+const (
+	FieldTitle    = "title"    // 标题
+	FieldIntro    = "intro"    // 简介
+	FieldDirector = "director" // 导演
+	FieldRelease  = "release"  // 发行时间
+	FieldRuntime  = "runtime"  // 时长
+	FieldStudio   = "studio"   // 厂商
+	FieldSeries   = "series"   // 系列
+	FieldTags     = "tags"     // 标签
+	FieldCover    = "cover"    // 图片
+	FieldActors   = "actors"   // 演员
+	FieldRating   = "rating"   // 评分
+	FieldWriter   = "writer"   // 编剧
+)
+
+// MergeScraper 多源合并刮削器，
+// 对同一番号执行多个刮削器，并按字段优先级从各刮削结果中取值。
+type MergeScraper struct {
+	cfg     *util.ConfigStruct   // 程序配置
+	entries []Entry              // 参与合并的刮削器
+	names   []string             // 刮削成功的来源，按优先级排序
+	results map[string]IScraper  // 刮削成功的结果
+	errs    map[string]error     // 刮削失败的原因
+	sources map[string]string    // 各字段实际来源
+	cancels []context.CancelFunc // 各来源上下文取消函数
+	ctxs    []context.Context    // 刮削成功来源的上下文，携带暂存的缓存
+}
+
+// NewMergeScraper 返回一个被初始化的多源合并刮削对象
+//
+// entries Entry数组，传入参与合并的刮削器，需已按优先级排序，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func NewMergeScraper(entries []Entry, cfg *util.ConfigStruct) *MergeScraper {
+	return &MergeScraper{
+		cfg:     cfg,
+		entries: entries,
+		results: make(map[string]IScraper),
+		errs:    make(map[string]error),
+		sources: make(map[string]string),
+	}
+}
+
+// Fetch 依次执行所有刮削器，只要有一个刮削成功即视为成功
+func (s *MergeScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文依次执行所有刮削器，
+// 各刮削器使用各自的超时设置，获取数据完成后需调用 Close 释放上下文。
+func (s *MergeScraper) FetchContext(ctx context.Context, code string) error {
+	// 循环刮削器
+	for _, entry := range s.entries {
+		// 上下文是否已结束
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 获取刮削器上下文，合并结果验证成功后才写入缓存
+		sctx, cancel := entry.Context(util.WithCacheSession(ctx))
+		// 记录取消函数
+		s.cancels = append(s.cancels, cancel)
+		// 实例化
+		sc := entry.New(s.cfg)
+		// 刮削
+		if err := FetchContext(sctx, sc, code); err != nil {
+			s.errs[entry.Name] = err
+			continue
+		}
+
+		// 记录结果
+		s.ctxs = append(s.ctxs, sctx)
+		s.names = append(s.names, entry.Name)
+		s.results[entry.Name] = sc
+	}
+
+	// 是否全部失败
+	if len(s.names) == 0 {
+		return fmt.Errorf("%s [Merge]: 所有来源均刮削失败", code)
+	}
+
+	return nil
+}
+
+// CommitCache 将各刮削成功来源暂存的网页写入缓存，
+// 应在合并结果验证成功后调用。
+func (s *MergeScraper) CommitCache() {
+	for _, ctx := range s.ctxs {
+		util.CommitCache(ctx)
+	}
+}
+
+// Close 释放各来源所使用的上下文
+func (s *MergeScraper) Close() {
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+}
+
+// Site 返回参与合并的来源名称
+func (s *MergeScraper) Site() string {
+	return strings.Join(s.names, "+")
+}
+
+// Errors 返回刮削失败的来源及错误原因
+func (s *MergeScraper) Errors() map[string]error {
+	return s.errs
+}
+
+// Sources 返回各字段的实际来源，键为字段名称
+func (s *MergeScraper) Sources() map[string]string {
+	// 复制一份
+	sources := make(map[string]string, len(s.sources))
+	for k, v := range s.sources {
+		sources[k] = v
+	}
+
+	return sources
+}
+
+// 获取字段的来源顺序，配置的优先级在前，其余按刮削器优先级
+func (s *MergeScraper) order(field string) []string {
+	// 定义顺序
+	var names []string
+	// 已加入的来源
+	added := make(map[string]bool)
+
+	// 获取配置的优先级
+	if s.cfg != nil {
+		for _, want := range s.cfg.Merge.Fields[field] {
+			for _, name := range s.names {
+				if strings.EqualFold(want, name) && !added[name] {
+					names = append(names, name)
+					added[name] = true
+				}
+			}
+		}
+	}
+
+	// 加入其余来源
+	for _, name := range s.names {
+		if !added[name] {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// 按字段优先级获取字符串数据
+func (s *MergeScraper) pickString(field string, get func(IScraper) string) string {
+	// 循环来源
+	for _, name := range s.order(field) {
+		// 获取数据
+		val := strings.TrimSpace(get(s.results[name]))
+		// 是否为空
+		if val == "" || val == "0" {
+			continue
+		}
+		// 记录来源
+		s.sources[field] = name
+
+		return val
+	}
+
+	return ""
+}
+
+// GetTitle 获取名称
+func (s *MergeScraper) GetTitle() string {
+	return s.pickString(FieldTitle, IScraper.GetTitle)
+}
+
+// GetIntro 获取简介
+func (s *MergeScraper) GetIntro() string {
+	return s.pickString(FieldIntro, IScraper.GetIntro)
+}
+
+// GetDirector 获取导演
+func (s *MergeScraper) GetDirector() string {
+	return s.pickString(FieldDirector, IScraper.GetDirector)
+}
+
+// GetRelease 发行时间
+func (s *MergeScraper) GetRelease() string {
+	return s.pickString(FieldRelease, IScraper.GetRelease)
+}
+
+// GetRuntime 获取时长
+func (s *MergeScraper) GetRuntime() string {
+	return s.pickString(FieldRuntime, IScraper.GetRuntime)
+}
+
+// GetStudio 获取厂商
+func (s *MergeScraper) GetStudio() string {
+	return s.pickString(FieldStudio, IScraper.GetStudio)
+}
+
+// GetSeries 获取系列
+func (s *MergeScraper) GetSeries() string {
+	return s.pickString(FieldSeries, IScraper.GetSeries)
+}
+
+// GetCover 获取图片
+func (s *MergeScraper) GetCover() string {
+	return s.pickString(FieldCover, IScraper.GetCover)
+}
+
+// GetTags 获取标签
+func (s *MergeScraper) GetTags() []string {
+	// 循环来源
+	for _, name := range s.order(FieldTags) {
+		// 获取数据
+		tags := s.results[name].GetTags()
+		// 是否为空
+		if len(tags) == 0 {
+			continue
+		}
+		// 记录来源
+		s.sources[FieldTags] = name
+
+		return tags
+	}
+
+	return nil
+}
+
+// GetActors 获取演员
+func (s *MergeScraper) GetActors() map[string]string {
+	// 循环来源
+	for _, name := range s.order(FieldActors) {
+		// 获取数据
+		actors := s.results[name].GetActors()
+		// 是否为空
+		if !hasActors(actors) {
+			continue
+		}
+		// 记录来源
+		s.sources[FieldActors] = name
+
+		return actors
+	}
+
+	return nil
+}
+
+// GetRating 获取评分
+func (s *MergeScraper) GetRating() (float64, int) {
+	// 循环来源
+	for _, name := range s.order(FieldRating) {
+		// 获取数据
+		rating, votes := GetRating(s.results[name])
+		// 是否为空
+		if rating <= 0 {
+			continue
+		}
+		// 记录来源
+		s.sources[FieldRating] = name
+
+		return rating, votes
+	}
+
+	return 0, 0
+}
+
+// GetWriter 获取编剧
+func (s *MergeScraper) GetWriter() string {
+	return s.pickString(FieldWriter, GetWriter)
+}
+
+// GetURI 获取首个来源的页面地址
+func (s *MergeScraper) GetURI() string {
+	return s.results[s.names[0]].GetURI()
+}
+
+// GetNumber 获取首个来源的番号
+func (s *MergeScraper) GetNumber() string {
+	return s.results[s.names[0]].GetNumber()
+}
+
+// 检查演员列表中是否有有效的演员名称
+func hasActors(actors map[string]string) bool {
+	for name := range actors {
+		if strings.TrimSpace(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,225 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ylqjgm/AVMeta/pkg/util"
+)
+
+// Constructor 刮削器构造函数，通过配置信息返回一个被初始化的刮削对象
+type Constructor func(cfg *util.ConfigStruct) IScraper
+
+// Entry 刮削器注册信息
+type Entry struct {
+	Name       string        // 刮削器名称
+	Priority   int           // 优先级，数值越小越优先
+	Pattern    string        // 番号匹配正则，为空则匹配所有番号
+	Timeout    time.Duration // 刮削超时时间，0 为不限制
+	Uncensored bool          // 是否为无码影片来源
+	New        Constructor   // 构造函数
+}
+
+// Context 返回应用了刮削器超时设置的上下文，
+// 刮削及获取数据完成后需调用返回的取消函数。
+//
+// ctx 上下文参数，传入父级上下文。
+func (e Entry) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	// 是否设置了超时
+	if e.Timeout > 0 {
+		return context.WithTimeout(ctx, e.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// Registry 刮削器注册表
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// DefaultRegistry 默认刮削器注册表，各刮削器均注册于此
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 返回一个空的刮削器注册表
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]Entry)}
+}
+
+// Register 将刮削器注册到默认注册表中，
+// 返回值仅用于在包变量初始化时完成注册。
+//
+// e Entry结构体，传入刮削器注册信息。
+func Register(e Entry) bool {
+	DefaultRegistry.Register(e)
+
+	return true
+}
+
+// Register 注册刮削器，同名刮削器将被覆盖
+//
+// e Entry结构体，传入刮削器注册信息。
+func (r *Registry) Register(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[strings.ToLower(e.Name)] = e
+}
+
+// Names 返回所有已注册刮削器名称，按默认优先级排序
+func (r *Registry) Names() []string {
+	// 获取全部注册信息
+	entries := r.all()
+	// 排序
+	sortEntries(entries)
+
+	// 定义名称数组
+	names := make([]string, 0, len(entries))
+	// 循环加入
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+
+	return names
+}
+
+// Lookup 根据番号及配置信息，返回可用于刮削的刮削器列表，
+// 列表已按优先级排序，并已应用配置中的启用、优先级及正则设置。
+//
+// code 字符串参数，传入番号信息，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func (r *Registry) Lookup(code string, cfg *util.ConfigStruct) ([]Entry, error) {
+	// 定义匹配结果
+	var matched []Entry
+
+	// 循环全部刮削器
+	for _, e := range r.all() {
+		// 应用配置
+		e, enable := applyScraperConfig(e, cfg)
+		// 是否被禁用
+		if !enable {
+			continue
+		}
+
+		// 没有正则则匹配所有番号
+		if e.Pattern != "" {
+			// 编译正则
+			re, err := regexp.Compile(e.Pattern)
+			// 检查
+			if err != nil {
+				return nil, fmt.Errorf("%s [Regexp]: %s", e.Name, err)
+			}
+			// 是否匹配
+			if !re.MatchString(code) {
+				continue
+			}
+		}
+
+		// 加入结果
+		matched = append(matched, e)
+	}
+
+	// 排序
+	sortEntries(matched)
+
+	return matched, nil
+}
+
+// Lookup 从默认注册表中查找可用于刮削的刮削器列表
+//
+// code 字符串参数，传入番号信息，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func Lookup(code string, cfg *util.ConfigStruct) ([]Entry, error) {
+	return DefaultRegistry.Lookup(code, cfg)
+}
+
+// Get 根据名称获取刮削器注册信息，名称不区分大小写，
+// 返回的注册信息已应用配置中的优先级、正则及超时设置，但不检查是否被禁用。
+//
+// name 字符串参数，传入刮削器名称，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func (r *Registry) Get(name string, cfg *util.ConfigStruct) (Entry, bool) {
+	r.mu.RLock()
+	// 查找
+	e, ok := r.entries[strings.ToLower(name)]
+	r.mu.RUnlock()
+	// 未找到
+	if !ok {
+		return Entry{}, false
+	}
+
+	// 应用配置
+	e, _ = applyScraperConfig(e, cfg)
+
+	return e, true
+}
+
+// Get 从默认注册表中根据名称获取刮削器注册信息
+//
+// name 字符串参数，传入刮削器名称，
+// cfg ConfigStruct结构体，传入程序配置信息。
+func Get(name string, cfg *util.ConfigStruct) (Entry, bool) {
+	return DefaultRegistry.Get(name, cfg)
+}
+
+// 获取全部注册信息
+func (r *Registry) all() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// 定义数组
+	entries := make([]Entry, 0, len(r.entries))
+	// 循环加入
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// 应用刮削器配置，返回新的注册信息及是否启用
+func applyScraperConfig(e Entry, cfg *util.ConfigStruct) (Entry, bool) {
+	// 检查配置
+	if cfg == nil || cfg.Scraper == nil {
+		return e, true
+	}
+
+	// 获取配置，viper 读取后的键名均为小写
+	sc, ok := cfg.Scraper[strings.ToLower(e.Name)]
+	// 没有配置
+	if !ok {
+		return e, true
+	}
+
+	// 覆盖优先级
+	if sc.Priority != 0 {
+		e.Priority = sc.Priority
+	}
+	// 覆盖正则
+	if sc.Regexp != "" {
+		e.Pattern = sc.Regexp
+	}
+	// 覆盖超时
+	if sc.Timeout > 0 {
+		e.Timeout = time.Duration(sc.Timeout) * time.Second
+	}
+
+	return e, !sc.Disable
+}
+
+// 按优先级排序，优先级相同则按名称排序
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Priority != entries[j].Priority {
+			return entries[i].Priority < entries[j].Priority
+		}
+
+		return entries[i].Name < entries[j].Name
+	})
+}
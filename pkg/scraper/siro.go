@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,9 +12,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:     "Siro",
+	Priority: 60,
+	Pattern:  `^(siro|abp|[0-9]{3,4}[a-zA-Z]{2,5})-[0-9]{3,4}`,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewSiroScraper(cfg.Base.Proxy)
+	},
+})
+
 // SiroScraper siro网站刮削器
 type SiroScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	number string            // 最终番号
 	root   *goquery.Document // 根节点
@@ -28,6 +40,13 @@ func NewSiroScraper(proxy string) *SiroScraper {
 
 // Fetch 刮削
 func (s *SiroScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *SiroScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToUpper(code)
 	// 定义Cookies
@@ -43,7 +62,7 @@ func (s *SiroScraper) Fetch(code string) error {
 	// 组合地址
 	uri := fmt.Sprintf("https://www.mgstage.com/product/product_detail/%s/", s.number)
 	// 打开链接
-	root, err := util.GetRoot(uri, s.Proxy, cookies)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, cookies)
 	// 检查
 	if err != nil {
 		return err
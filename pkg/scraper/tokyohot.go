@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -11,9 +12,21 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// 注册刮削器
+var _ = Register(Entry{
+	Name:       "TokyoHot",
+	Priority:   20,
+	Pattern:    `(^red-\d{3}|n\d{4})`,
+	Uncensored: true,
+	New: func(cfg *util.ConfigStruct) IScraper {
+		return NewTokyoHotScraper(cfg.Base.Proxy)
+	},
+})
+
 // TokyoHotScraper tokyohot网站刮削器
 type TokyoHotScraper struct {
 	Proxy  string            // 代理配置
+	ctx    context.Context   // 上下文
 	uri    string            // 页面地址
 	number string            // 最终番号
 	root   *goquery.Document // 根节点
@@ -28,6 +41,13 @@ func NewTokyoHotScraper(proxy string) *TokyoHotScraper {
 
 // Fetch 刮削
 func (s *TokyoHotScraper) Fetch(code string) error {
+	return s.FetchContext(context.Background(), code)
+}
+
+// FetchContext 携带上下文刮削
+func (s *TokyoHotScraper) FetchContext(ctx context.Context, code string) error {
+	// 设置上下文
+	s.ctx = ctx
 	// 设置番号
 	s.number = strings.ToLower(code)
 	// 获取编号
@@ -40,7 +60,7 @@ func (s *TokyoHotScraper) Fetch(code string) error {
 	// 组合地址
 	uri := fmt.Sprintf("https://my.tokyo-hot.com%s?lang=zh-TW", id)
 	// 打开链接
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查
 	if err != nil {
 		return err
@@ -59,7 +79,7 @@ func (s *TokyoHotScraper) search() (id string, err error) {
 	// 组合地址
 	uri := fmt.Sprintf("https://my.tokyo-hot.com/product/?q=%s&x=0&y=0&lang=zh-TW", s.number)
 	// 获取节点
-	root, err := util.GetRoot(uri, s.Proxy, nil)
+	root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 	// 检查错误
 	if err != nil {
 		return "", fmt.Errorf("%s [Search]: %s", uri, err)
@@ -117,7 +137,7 @@ func (s *TokyoHotScraper) GetRelease() string {
 // GetRuntime 获取时长
 func (s *TokyoHotScraper) GetRuntime() string {
 	// 获取时长
-	strTime := strings.TrimSpace(s.root.Find(`dt:contains("収録時間"`).Next().Text())
+	strTime := strings.TrimSpace(s.root.Find(`dt:contains("収録時間")`).Next().Text())
 	// 是否正确获取
 	if strTime != "" {
 		// 搜索正则
@@ -186,14 +206,14 @@ func (s *TokyoHotScraper) GetActors() map[string]string {
 		// 组合地址
 		uri := fmt.Sprintf("https://my.tokyo-hot.com%s", link)
 		// 打开链接
-		root, err := util.GetRoot(uri, s.Proxy, nil)
+		root, err := util.GetRootContext(s.ctx, uri, s.Proxy, nil)
 		// 检查错误
 		if err != nil {
 			return
 		}
 
 		// 获取演员图片
-		img, _ := item.Find(`#profile img`).Attr("src")
+		img, _ := root.Find(`#profile img`).Attr("src")
 		// 获取演员名字
 		actors[strings.TrimSpace(root.Find(`.pagetitle h2`).Text())] = strings.TrimSpace(img)
 	})
@@ -0,0 +1,334 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheInfo 缓存统计信息
+type CacheInfo struct {
+	Path    string // 缓存目录
+	Files   int    // 缓存文件数量
+	Size    int64  // 缓存总大小，单位字节
+	Expired int    // 已过期文件数量
+}
+
+// 缓存状态
+var cache = struct {
+	sync.Mutex
+	cfg    CacheStruct // 缓存配置
+	size   int64       // 当前缓存大小
+	loaded bool        // 是否已统计缓存大小
+}{}
+
+// 缓存文件信息
+type cacheFile struct {
+	path string
+	size int64
+	mod  time.Time
+}
+
+// SetCache 设置响应缓存配置
+//
+// c CacheStruct结构体，传入缓存配置信息。
+func SetCache(c CacheStruct) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.cfg = c
+	cache.loaded = false
+}
+
+// CacheDir 获取缓存目录，位于程序执行路径下的 cache 文件夹
+func CacheDir() string {
+	return GetRunPath() + "/cache"
+}
+
+// CacheStats 统计缓存目录信息，并返回统计信息及错误信息
+func CacheStats() (*CacheInfo, error) {
+	// 获取缓存文件
+	files, err := cacheFiles()
+	// 检查错误
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取有效期
+	cache.Lock()
+	ttl := cacheTTL(cache.cfg)
+	cache.Unlock()
+
+	// 统计
+	info := &CacheInfo{Path: CacheDir(), Files: len(files)}
+	for _, f := range files {
+		info.Size += f.size
+		if ttl > 0 && time.Since(f.mod) > ttl {
+			info.Expired++
+		}
+	}
+
+	return info, nil
+}
+
+// ClearCache 清空缓存目录
+func ClearCache() error {
+	cache.Lock()
+	defer cache.Unlock()
+
+	// 重置大小
+	cache.size = 0
+	cache.loaded = true
+
+	return os.RemoveAll(CacheDir())
+}
+
+// 缓存会话上下文键
+type cacheSessionKey struct{}
+
+// 只读缓存上下文键
+type cacheReadOnlyKey struct{}
+
+// 缓存会话，暂存刮削过程中请求到的网页，刮削成功后才写入缓存
+type cacheSession struct {
+	sync.Mutex
+	entries map[string][]byte
+}
+
+// WithCacheSession 返回携带缓存会话的上下文，
+// 使用该上下文请求到的网页先暂存在会话中，调用 CommitCache 后才写入缓存，
+// 避免将无结果、年龄确认或验证页面等失败响应缓存下来。
+//
+// ctx 上下文参数，传入刮削所使用的上下文。
+func WithCacheSession(ctx context.Context) context.Context {
+	// 只读缓存，不创建会话，请求到的网页不会被暂存
+	if ctx.Value(cacheReadOnlyKey{}) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, cacheSessionKey{}, &cacheSession{entries: make(map[string][]byte)})
+}
+
+// WithReadOnlyCache 返回只读缓存的上下文，使用该上下文刮削时仍会读取已有缓存，
+// 但不会写入新的缓存，用于仅输出整理计划等不应写入任何文件的场景。
+//
+// ctx 上下文参数，传入刮削所使用的上下文。
+func WithReadOnlyCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheReadOnlyKey{}, true)
+}
+
+// CommitCache 将上下文缓存会话中暂存的网页写入缓存，
+// 应在刮削结果验证成功后调用，未携带缓存会话时不做任何操作。
+//
+// ctx 上下文参数，传入 WithCacheSession 返回的上下文。
+func CommitCache(ctx context.Context) {
+	// 获取会话
+	cs, ok := ctx.Value(cacheSessionKey{}).(*cacheSession)
+	if !ok {
+		return
+	}
+
+	cs.Lock()
+	defer cs.Unlock()
+
+	// 写入缓存
+	for key, data := range cs.entries {
+		writeCache(key, data)
+	}
+	cs.entries = make(map[string][]byte)
+}
+
+// 暂存网页到上下文缓存会话，未携带缓存会话时不缓存
+func stageCache(ctx context.Context, key string, data []byte) {
+	// 获取会话
+	cs, ok := ctx.Value(cacheSessionKey{}).(*cacheSession)
+	if !ok {
+		return
+	}
+
+	cs.Lock()
+	cs.entries[key] = data
+	cs.Unlock()
+}
+
+// 获取缓存键，包含请求时携带的 cookie，避免不同 cookie 的页面互相覆盖
+func cacheKey(uri string, cookies []*http.Cookie) string {
+	// 无 cookie
+	if len(cookies) == 0 {
+		return uri
+	}
+
+	// 按名称排序
+	pairs := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	sort.Strings(pairs)
+
+	return uri + "\n" + strings.Join(pairs, "; ")
+}
+
+// 读取缓存，未启用、不存在或已过期则返回false
+func readCache(key string) ([]byte, bool) {
+	cache.Lock()
+	cfg := cache.cfg
+	cache.Unlock()
+
+	// 是否启用
+	if !cfg.Enable {
+		return nil, false
+	}
+
+	// 缓存路径
+	file := cachePath(key)
+	// 获取文件信息
+	info, err := os.Stat(file)
+	// 检查
+	if err != nil {
+		return nil, false
+	}
+	// 是否过期
+	if ttl := cacheTTL(cfg); ttl > 0 && time.Since(info.ModTime()) > ttl {
+		return nil, false
+	}
+
+	// 读取数据
+	data, err := ioutil.ReadFile(file)
+	// 检查
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// 写入缓存，超出最大容量时删除最旧的缓存
+func writeCache(key string, data []byte) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	// 是否启用
+	if !cache.cfg.Enable {
+		return
+	}
+
+	// 缓存路径
+	file := cachePath(key)
+	// 创建目录
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return
+	}
+
+	// 旧缓存大小
+	old := GetFileSize(file)
+
+	// 先写入临时文件再重命名，避免读取到不完整的缓存
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+
+	// 统计缓存大小
+	if !cache.loaded {
+		cache.size = 0
+		if files, err := cacheFiles(); err == nil {
+			for _, f := range files {
+				cache.size += f.size
+			}
+		}
+		cache.loaded = true
+	} else {
+		cache.size += int64(len(data)) - old
+	}
+
+	// 检查容量
+	maxSize := int64(cache.cfg.MaxSize) * 1024 * 1024
+	if maxSize > 0 && cache.size > maxSize {
+		cache.size = pruneCache(maxSize * 9 / 10)
+	}
+}
+
+// 删除最旧的缓存直至小于指定大小，并返回剩余大小
+func pruneCache(limit int64) int64 {
+	// 获取缓存文件
+	files, err := cacheFiles()
+	// 检查
+	if err != nil {
+		return 0
+	}
+
+	// 统计大小
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+
+	// 按修改时间排序，旧的在前
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].mod.Before(files[j].mod)
+	})
+
+	// 删除旧缓存
+	for _, f := range files {
+		if size <= limit {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			size -= f.size
+		}
+	}
+
+	return size
+}
+
+// 获取所有缓存文件
+func cacheFiles() ([]cacheFile, error) {
+	// 文件列表
+	var files []cacheFile
+
+	// 遍历缓存目录
+	err := filepath.Walk(CacheDir(), func(filePath string, f os.FileInfo, err error) error {
+		// 错误
+		if f == nil {
+			// 缓存目录不存在
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// 忽略目录
+		if f.IsDir() {
+			return nil
+		}
+
+		// 加入列表
+		files = append(files, cacheFile{path: filePath, size: f.Size(), mod: f.ModTime()})
+
+		return nil
+	})
+
+	return files, err
+}
+
+// 获取缓存文件路径，以缓存键的md5值作为文件名
+func cachePath(key string) string {
+	// 计算md5
+	sum := MD5String(key)
+
+	return CacheDir() + "/" + sum[:2] + "/" + sum
+}
+
+// 获取缓存有效期
+func cacheTTL(c CacheStruct) time.Duration {
+	return time.Duration(c.TTL) * time.Hour
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheSession(t *testing.T) {
+	// 记录请求次数，响应内容包含 cookie
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		c, _ := r.Cookie("age_check_done")
+		_, _ = fmt.Fprintf(w, "%s %v", r.URL.Path, c)
+	}))
+	defer srv.Close()
+
+	// 启用缓存
+	SetCache(CacheStruct{Enable: true})
+	defer SetCache(CacheStruct{})
+	if err := ClearCache(); err != nil {
+		t.Fatalf("ClearCache() error = %v", err)
+	}
+	defer func() { _ = ClearCache() }()
+
+	// 请求并返回本次是否访问了网站
+	fetch := func(ctx context.Context, path string, cookies []*http.Cookie) bool {
+		before := atomic.LoadInt32(&hits)
+		if _, err := GetResultContext(ctx, srv.URL+path, "", cookies); err != nil {
+			t.Fatalf("GetResultContext(%s) error = %v", path, err)
+		}
+		return atomic.LoadInt32(&hits) > before
+	}
+
+	// 未携带缓存会话时不缓存
+	fetch(context.Background(), "/plain", nil)
+	if !fetch(context.Background(), "/plain", nil) {
+		t.Error("未携带缓存会话的响应被缓存")
+	}
+
+	// 未提交的会话不缓存
+	ctx := WithCacheSession(context.Background())
+	fetch(ctx, "/failed", nil)
+	if !fetch(context.Background(), "/failed", nil) {
+		t.Error("未提交的响应被缓存")
+	}
+
+	// 提交后缓存
+	ctx = WithCacheSession(context.Background())
+	fetch(ctx, "/detail", nil)
+	CommitCache(ctx)
+	if fetch(context.Background(), "/detail", nil) {
+		t.Error("已提交的响应未被缓存")
+	}
+
+	// 只读缓存可以读取已有缓存，但提交后不写入
+	ctx = WithCacheSession(WithReadOnlyCache(context.Background()))
+	if fetch(ctx, "/detail", nil) {
+		t.Error("只读缓存未读取已有缓存")
+	}
+	fetch(ctx, "/plan", nil)
+	CommitCache(ctx)
+	if !fetch(context.Background(), "/plan", nil) {
+		t.Error("只读缓存写入了缓存")
+	}
+
+	// 携带 cookie 的请求使用独立的缓存
+	cookies := []*http.Cookie{{Name: "age_check_done", Value: "1"}}
+	if !fetch(context.Background(), "/detail", cookies) {
+		t.Error("携带 cookie 的请求使用了无 cookie 的缓存")
+	}
+}
@@ -0,0 +1,287 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// 默认请求超时时间
+	defaultTimeout = 60 * time.Second
+	// 最大重试等待时间
+	maxBackoff = 30 * time.Second
+)
+
+// 共享网络状态
+var network = struct {
+	sync.Mutex
+	cfg      NetworkStruct           // 网络配置
+	clients  map[string]*http.Client // 共享客户端，以代理地址为键
+	limiters map[string]*limiter     // 域名限速器，以域名为键
+	recorder *Recorder               // 请求录制对象
+}{
+	clients:  make(map[string]*http.Client),
+	limiters: make(map[string]*limiter),
+}
+
+// SetNetwork 设置网络配置，
+// 已创建的共享客户端及域名限速器将被重置。
+//
+// n NetworkStruct结构体，传入网络配置信息。
+func SetNetwork(n NetworkStruct) {
+	network.Lock()
+	defer network.Unlock()
+
+	network.cfg = n
+	network.clients = make(map[string]*http.Client)
+	network.limiters = make(map[string]*limiter)
+}
+
+// SetRecorder 设置请求录制或回放，
+// 设置后所有通过 MakeRequest 发起的请求均将被录制或从录制文件中回放，
+// mode 为空时取消录制。
+//
+// mode 字符串参数，传入录制模式，参见常量定义，
+// dir 字符串参数，传入录制文件目录。
+func SetRecorder(mode, dir string) error {
+	// 录制对象
+	var r *Recorder
+	// 是否需要录制
+	if mode != "" {
+		var err error
+		// 实例化
+		r, err = NewRecorder(mode, dir)
+		// 检查
+		if err != nil {
+			return err
+		}
+	}
+
+	network.Lock()
+	defer network.Unlock()
+
+	network.recorder = r
+	network.clients = make(map[string]*http.Client)
+
+	return nil
+}
+
+// 获取共享http客户端，相同代理的请求共用同一个连接池
+func createHTTPClient(proxy string) *http.Client {
+	network.Lock()
+	defer network.Unlock()
+
+	// 是否已创建
+	if client, ok := network.clients[proxy]; ok {
+		return client
+	}
+
+	// 初始化
+	transport := &http.Transport{
+		/* #nosec */
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	// 如果有代理
+	if proxy != "" {
+		// 解析代理地址
+		proxyURI := func(_ *http.Request) (*url.URL, error) {
+			return url.Parse(proxy)
+		}
+		// 加入代理
+		transport.Proxy = proxyURI
+	}
+
+	// 超时时间
+	timeout := defaultTimeout
+	if network.cfg.Timeout > 0 {
+		timeout = time.Duration(network.cfg.Timeout) * time.Second
+	}
+
+	// 创建客户端
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	// 是否录制
+	if network.recorder != nil {
+		client.Transport = network.recorder.Wrap(transport)
+	}
+	network.clients[proxy] = client
+
+	return client
+}
+
+// 获取指定域名的限速器，未限速则返回nil
+func hostLimiter(host string) *limiter {
+	network.Lock()
+	defer network.Unlock()
+
+	// 是否已创建
+	if l, ok := network.limiters[host]; ok {
+		return l
+	}
+
+	// 默认限速
+	rate, burst := network.cfg.Rate, network.cfg.Burst
+	// 查找域名限速
+	for _, h := range network.cfg.Hosts {
+		if strings.EqualFold(h.Host, host) {
+			rate, burst = h.Rate, h.Burst
+			break
+		}
+	}
+
+	// 是否限速
+	var l *limiter
+	if rate > 0 {
+		l = newLimiter(rate, burst)
+	}
+	network.limiters[host] = l
+
+	return l
+}
+
+// 获取重试次数及首次重试等待时间
+func retryPolicy() (int, time.Duration) {
+	network.Lock()
+	defer network.Unlock()
+
+	return network.cfg.Retry, time.Duration(network.cfg.Backoff) * time.Millisecond
+}
+
+// 检查请求结果是否需要重试
+func shouldRetry(ctx context.Context, status int, err error) bool {
+	// 上下文结束则不再重试
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// 网络错误
+	if err != nil {
+		return isTransient(err)
+	}
+
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// 检查是否为临时性网络错误
+func isTransient(err error) bool {
+	// 超时或临时错误
+	var ne net.Error
+	if errors.As(err, &ne) && (ne.Timeout() || ne.Temporary()) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// 计算第 attempt 次重试的等待时间，
+// 优先使用服务器返回的 Retry-After，否则指数递增并加入随机抖动。
+func backoffDelay(base time.Duration, attempt int, res *http.Response) time.Duration {
+	// 服务器指定等待时间
+	if res != nil {
+		if sec, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && sec > 0 {
+			delay := time.Duration(sec) * time.Second
+			if delay > maxBackoff {
+				delay = maxBackoff
+			}
+
+			return delay
+		}
+	}
+
+	// 指数递增
+	delay := base << uint(attempt)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	/* #nosec */
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// 等待指定时间，上下文结束时提前返回错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	// 定时器
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// 令牌桶限速器
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64   // 每秒生成令牌数
+	burst  float64   // 令牌桶容量
+	tokens float64   // 当前令牌数
+	last   time.Time // 上次更新时间
+}
+
+// 创建限速器
+func newLimiter(rate float64, burst int) *limiter {
+	// 最少可容纳一个令牌
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// 等待获取一个令牌，上下文结束时返回错误
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		// 补充令牌
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		// 是否有可用令牌
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+
+			return nil
+		}
+
+		// 计算等待时间
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		// 等待
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
@@ -12,15 +12,23 @@ type BaseStruct struct {
 
 // PathStruct 配置信息路径节点
 type PathStruct struct {
+	Input     string   // 要整理的目录，为空则为程序执行路径
+	Output    string   // 输出目录，成功及失败目录位于其中，为空则为程序执行路径
 	Success   string   // 成功存储目录
 	Fail      string   // 失败存储目录
 	Directory string   // 影片存储路径格式
+	Filename  string   // 影片文件名称格式，不含扩展名
 	Filter    []string // 文件名过滤规则
+	Mode      string   // 整理方式: move, copy, hardlink, symlink
+	Exclude   []string // 排除的文件或目录，glob 格式
+	MinSize   int      // 最小文件大小，单位MB，用于跳过预告片、样片等
+	Exts      []string // 视频扩展名列表
 }
 
 // MediaStruct 配置信息媒体库节点
 type MediaStruct struct {
 	Library   string // 媒体库类型
+	Profile   string // nfo 格式，可选 emby、kodi、jellyfin
 	URL       string // Emby访问地址
 	API       string // Emby API Key
 	SecretID  string // 腾讯云 SecretId
@@ -33,13 +41,62 @@ type SiteStruct struct {
 	JavDB  string // javdb免翻地址
 }
 
+// NetworkStruct 配置信息网络节点
+type NetworkStruct struct {
+	Timeout int          // 单次请求超时时间，单位秒
+	Rate    float64      // 每个域名每秒最大请求数，0 为不限制
+	Burst   int          // 每个域名允许的突发请求数
+	Retry   int          // 失败重试次数
+	Backoff int          // 首次重试等待时间，单位毫秒，之后指数递增
+	Hosts   []HostStruct // 指定域名的限速配置
+}
+
+// HostStruct 配置信息域名限速节点
+type HostStruct struct {
+	Host  string  // 域名
+	Rate  float64 // 每秒最大请求数，0 为不限制
+	Burst int     // 允许的突发请求数
+}
+
+// CacheStruct 配置信息缓存节点
+type CacheStruct struct {
+	Enable  bool // 是否启用响应缓存
+	TTL     int  // 缓存有效期，单位小时，0 为永久有效
+	MaxSize int  // 缓存最大容量，单位MB，0 为不限制
+}
+
+// ScraperStruct 配置信息刮削器节点
+type ScraperStruct struct {
+	Disable  bool   // 是否禁用
+	Priority int    // 优先级，数值越小越优先，0 为默认
+	Regexp   string // 番号匹配正则，为空则使用默认
+	Timeout  int    // 刮削超时时间，单位秒，0 为不限制
+}
+
+// MergeStruct 配置信息多源合并节点
+type MergeStruct struct {
+	Enable bool                // 是否启用多源合并
+	Fields map[string][]string // 各字段来源优先级，键为字段名称
+}
+
+// ImageStruct 配置信息内嵌图片节点
+type ImageStruct struct {
+	MaxDimension int // 内嵌图片最长边最大像素，超出时等比缩小，0 为不限制
+	MaxSize      int // 内嵌图片最大大小，单位KB，超出时缩小并重新编码，0 为不限制
+}
+
 // ConfigStruct 程序配置信息结构
 type ConfigStruct struct {
-	Base  BaseStruct  // 基础配置
-	Path  PathStruct  // 路径配置
-	Media MediaStruct // 媒体库配置
-	Site  SiteStruct  // 免翻地址配置
-	Code  []string    // 优先匹配番号
+	Base    BaseStruct               // 基础配置
+	Path    PathStruct               // 路径配置
+	Media   MediaStruct              // 媒体库配置
+	Site    SiteStruct               // 免翻地址配置
+	Network NetworkStruct            // 网络配置
+	Cache   CacheStruct              // 缓存配置
+	Scraper map[string]ScraperStruct // 刮削器配置，键为刮削器名称
+	Merge   MergeStruct              // 多源合并配置
+	Image   ImageStruct              // 内嵌图片配置
+	Code    []string                 // 优先匹配番号
 }
 
 // GetConfig 读取配置信息，返回配置信息对象，
@@ -51,6 +108,8 @@ func GetConfig() (*ConfigStruct, error) {
 	viper.SetConfigType("yaml")
 	// 添加当前执行路径为配置路径
 	viper.AddConfigPath(".")
+	// 设置默认值，兼容缺少新配置节点的旧配置文件
+	setDefaults()
 	// 读取配置信息
 	err := viper.ReadInConfig()
 	// 读取配置
@@ -101,10 +160,15 @@ func WriteConfig() (*ConfigStruct, error) {
 			Success:   "success",
 			Fail:      "fail",
 			Directory: "{number}",
+			Filename:  DefaultFilename,
 			Filter:    []string{"thz.la"},
+			Mode:      ModeMove,
+			Exclude:   []string{"*sample*", "*trailer*"},
+			Exts:      DefaultVideoExts,
 		},
 		Media: MediaStruct{
 			Library:   "nfo",
+			Profile:   "emby",
 			URL:       "",
 			API:       "",
 			SecretID:  "",
@@ -114,6 +178,8 @@ func WriteConfig() (*ConfigStruct, error) {
 			JavBus: "https://www.javbus.com/",
 			JavDB:  "https://javdb4.com/",
 		},
+		Network: defaultNetwork(),
+		Cache:   defaultCache(),
 	}
 
 	// 设置数据
@@ -121,7 +187,58 @@ func WriteConfig() (*ConfigStruct, error) {
 	viper.Set("path", cfg.Path)
 	viper.Set("media", cfg.Media)
 	viper.Set("site", cfg.Site)
+	viper.Set("network", cfg.Network)
+	viper.Set("cache", cfg.Cache)
+	viper.Set("scraper", cfg.Scraper)
+	viper.Set("merge", cfg.Merge)
+	viper.Set("image", cfg.Image)
 	viper.Set("code", cfg.Code)
 
 	return cfg, viper.SafeWriteConfig()
 }
+
+// 默认网络配置
+func defaultNetwork() NetworkStruct {
+	return NetworkStruct{
+		Timeout: 60,
+		Rate:    1,
+		Burst:   2,
+		Retry:   3,
+		Backoff: 1000,
+	}
+}
+
+// 默认缓存配置
+func defaultCache() CacheStruct {
+	return CacheStruct{
+		Enable:  true,
+		TTL:     168,
+		MaxSize: 512,
+	}
+}
+
+// 设置配置默认值
+func setDefaults() {
+	// 整理方式
+	viper.SetDefault("path.mode", ModeMove)
+	// 文件名称格式
+	viper.SetDefault("path.filename", DefaultFilename)
+	// 视频扩展名列表
+	viper.SetDefault("path.exts", DefaultVideoExts)
+	// nfo 格式，默认与原有格式兼容
+	viper.SetDefault("media.profile", "emby")
+
+	// 网络配置
+	n := defaultNetwork()
+	viper.SetDefault("network.timeout", n.Timeout)
+	viper.SetDefault("network.rate", n.Rate)
+	viper.SetDefault("network.burst", n.Burst)
+	viper.SetDefault("network.retry", n.Retry)
+	viper.SetDefault("network.backoff", n.Backoff)
+
+	// 缓存配置
+	c := defaultCache()
+	viper.SetDefault("cache.enable", c.Enable)
+	viper.SetDefault("cache.ttl", c.TTL)
+	viper.SetDefault("cache.maxsize", c.MaxSize)
+}
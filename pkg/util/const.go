@@ -9,17 +9,5 @@ const (
 	HEYZO = "HEYZO"
 )
 
-// 定义变量
-var (
-	// 定义扩展列表
-	videoExts = map[string]string{
-		".avi":  ".avi",
-		".flv":  ".flv",
-		".mkv":  ".mkv",
-		".mov":  ".mov",
-		".mp4":  ".mp4",
-		".rmvb": ".rmvb",
-		".ts":   ".ts",
-		".wmv":  ".wmv",
-	}
-)
+// DefaultVideoExts 默认视频扩展名列表
+var DefaultVideoExts = []string{".avi", ".flv", ".mkv", ".mov", ".mp4", ".rmvb", ".ts", ".wmv"}
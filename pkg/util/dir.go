@@ -3,9 +3,7 @@ package util
 import (
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
@@ -17,49 +15,34 @@ type NfoFile struct {
 	Dir    string
 }
 
-// WalkDir 遍历指定目录下的视频文件，
-// 返回文件路径列表及错误信息。
+// WalkDir 遍历指定目录下需要整理的视频文件，
+// 将跳过成功、失败目录及配置中排除的文件，返回文件路径列表及错误信息。
 //
 // dirPath 字符串参数，传入要遍历的目录路径，
-// success 字符串参数，传入要过滤的success目录名称，
-// fail 字符串参数，传入要过滤的fail目录名称。
-func WalkDir(dirPath, success, fail string) ([]string, error) {
+// cfg 配置信息，用以读取过滤规则。
+func WalkDir(dirPath string, cfg *ConfigStruct) ([]string, error) {
 	// 定义文件列表
 	var files []string
 
+	// 文件过滤
+	filter := NewFileFilter(dirPath, cfg)
+
 	// 遍历目录
 	err := filepath.Walk(dirPath, func(filePath string, f os.FileInfo, err error) error {
 		// 错误
 		if f == nil {
 			return err
 		}
-		// 忽略目录
+		// 跳过排除的目录
 		if f.IsDir() {
+			if filePath != dirPath && filter.SkipDir(filePath) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// 检测是否为过滤目录
-		if strings.Contains(
-			strings.ToUpper(filePath),
-			strings.ToUpper(success)) ||
-			strings.Contains(
-				strings.ToUpper(filePath),
-				strings.ToUpper(fail)) {
-			return nil
-		}
-
-		// 隐藏文件正则
-		rHidden := regexp.MustCompile(`^\.(.)*`)
-		// 检测是否为隐藏文件
-		if rHidden.MatchString(f.Name()) {
-			return nil
-		}
-
-		// 获取后缀并转换为小写
-		ext := strings.ToLower(path.Ext(filePath))
-
-		// 验证是否存在于后缀扩展名中
-		if _, ok := videoExts[ext]; ok {
+		// 验证是否为需要整理的视频文件
+		if filter.match(filePath, f) {
 			// 存在则加入扩展
 			files = append(files, filePath)
 		}
@@ -70,6 +53,37 @@ func WalkDir(dirPath, success, fail string) ([]string, error) {
 	return files, err
 }
 
+// InputPath 获取要整理的目录，未配置时为程序执行路径
+//
+// cfg 配置信息，用以读取整理目录。
+func InputPath(cfg *ConfigStruct) string {
+	return absPath(cfg.Path.Input)
+}
+
+// OutputPath 获取整理后的输出目录，成功及失败目录均位于其中，未配置时为程序执行路径
+//
+// cfg 配置信息，用以读取输出目录。
+func OutputPath(cfg *ConfigStruct) string {
+	return absPath(cfg.Path.Output)
+}
+
+// 获取绝对路径，为空时为程序执行路径
+func absPath(dir string) string {
+	// 未配置
+	if strings.TrimSpace(dir) == "" {
+		return GetRunPath()
+	}
+
+	// 转换为绝对路径
+	abs, err := filepath.Abs(dir)
+	// 检查错误
+	if err != nil {
+		return dir
+	}
+
+	return abs
+}
+
 // GetRunPath 获取程序当前执行路径
 func GetRunPath() string {
 	// 获取当前执行路径
@@ -124,3 +138,47 @@ func WalkNfo(dirPath string, files []NfoFile) ([]NfoFile, error) {
 
 	return files, nil
 }
+
+// WalkVSMeta 遍历当前目录下所有只有 .vsmeta 而没有 .nfo 的目录，
+// 每个目录返回一个 NfoFile，Path 为目录中的第一个 vsmeta 文件。
+//
+// dirPath 字符串参数，传入要遍历的目录路径，
+// files NfoFile列表参数，传入已有的文件列表，结果将追加在其后。
+func WalkVSMeta(dirPath string, files []NfoFile) ([]NfoFile, error) {
+	// 读取目录
+	r, err := ioutil.ReadDir(dirPath)
+	// 检查错误
+	if err != nil {
+		return nil, err
+	}
+
+	// 当前目录中的vsmeta
+	var vsmeta string
+	// 当前目录中是否有nfo
+	hasNfo := false
+	// 循环列表
+	for _, f := range r {
+		if f.IsDir() {
+			files, err = WalkVSMeta(dirPath+"/"+f.Name(), files)
+			if err != nil {
+				return files, err
+			}
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".nfo":
+			hasNfo = true
+		case ".vsmeta":
+			if vsmeta == "" {
+				vsmeta = dirPath + "/" + f.Name()
+			}
+		}
+	}
+
+	// 加入文件列表
+	if vsmeta != "" && !hasNfo {
+		files = append(files, NfoFile{Path: vsmeta, Dir: dirPath})
+	}
+
+	return files, nil
+}
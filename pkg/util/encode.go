@@ -1,12 +1,14 @@
 package util
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
+	"strings"
 )
 
 // MD5String 将指定字符串加密为 md5，
@@ -25,62 +27,84 @@ func MD5String(str string) string {
 // Base64 将指定文件编码为 Base64 字符串，
 // 返回编码信息及错误信息。
 //
-// file 字符串参数，传入文件路径
-func Base64(file string) (string, error) {
-	// 检查错误
-	f, err := os.Open(file)
-	// 如果出错
-	if err != nil {
-		return Base64ForURI(file)
+// file 字符串参数，传入文件路径或 http、https 远程链接，
+// proxy 字符串参数，传入读取远程链接时使用的代理地址
+func Base64(file, proxy string) (string, error) {
+	// 远程链接
+	if isRemote(file) {
+		return Base64ForURI(file, proxy)
 	}
-	// 关闭
-	defer f.Close()
 
-	// 初始化byte
-	buff := make([]byte, 500000)
-	// 读取文件
-	n, err := f.Read(buff)
+	// 打开文件
+	f, err := os.Open(file)
 	// 检查错误
 	if err != nil {
 		return "", err
 	}
+	// 关闭
+	defer f.Close()
 
-	// Base64编码
-	source := base64.StdEncoding.EncodeToString(buff[:n])
-
-	return source, nil
+	return Base64Reader(f)
 }
 
 // Base64ForURI 从远程文件获取 Base64
 // 返回编码信息及错误信息。
 //
-// uri 字符串参数，传入远程链接
-func Base64ForURI(uri string) (string, error) {
-	// 解析网址
-	u, err := url.Parse(uri)
-	if err != nil || u == nil {
+// uri 字符串参数，传入远程链接，
+// proxy 字符串参数，传入代理地址
+func Base64ForURI(uri, proxy string) (string, error) {
+	// 读取远程链接
+	body, err := readURI(context.Background(), uri, proxy)
+	// 检查错误
+	if err != nil {
 		return "", err
 	}
 
-	// 打开远程链接
-	res, err := http.Get(uri)
+	return base64.StdEncoding.EncodeToString(body), nil
+}
+
+// Base64Reader 将读取器中的全部内容流式编码为 Base64 字符串，
+// 不限制内容大小。
+//
+// r 读取器参数，传入要编码的内容
+func Base64Reader(r io.Reader) (string, error) {
+	var sb strings.Builder
+	// 编码器
+	enc := base64.NewEncoder(base64.StdEncoding, &sb)
+	// 流式编码
+	_, err := io.Copy(enc, r)
+	// 检查错误
 	if err != nil {
 		return "", err
 	}
-	// 关闭
-	defer res.Body.Close()
-
-	// 初始化byte
-	buff := make([]byte, 500000)
-	// 读取文件
-	n, err := res.Body.Read(buff)
+	// 写入剩余字节
+	err = enc.Close()
 	// 检查错误
 	if err != nil {
 		return "", err
 	}
 
-	// Base64编码
-	source := base64.StdEncoding.EncodeToString(buff[:n])
+	return sb.String(), nil
+}
+
+// 检查路径是否为 http、https 远程链接
+func isRemote(file string) bool {
+	// 解析网址
+	u, err := url.Parse(file)
+	// 检查
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// 携带上下文读取远程链接，使用共享请求客户端，支持代理、限速、重试及缓存
+func readURI(ctx context.Context, uri, proxy string) ([]byte, error) {
+	// 检查网址
+	if !isRemote(uri) {
+		return nil, fmt.Errorf("%s 不是有效的远程链接", uri)
+	}
 
-	return source, nil
+	return GetResultContext(ctx, uri, proxy, nil)
 }
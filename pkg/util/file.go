@@ -1,43 +1,81 @@
 package util
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 )
 
-// FailFile 将整理失败的文件存储到fail目录中
+// FailFile 将整理失败的文件及其字幕等附属文件存储到fail目录中
 //
 // file 字符串参数，传入失败文件路径，
-// fail 字符串参数，传入fail目录路径。
-func FailFile(file, fail string) {
-	// 获取运行路径
-	base := GetRunPath()
-	// 组合路径
-	base = base + "/" + fail
+// cfg 配置信息，用以读取fail目录。
+func FailFile(file string, cfg *ConfigStruct) {
+	// 失败目录中的路径
+	failPath := FailPath(file, cfg)
+	// 已位于失败目录
+	if failPath == file {
+		return
+	}
+	// 失败目录
+	base := filepath.Dir(failPath)
+
+	// 查找字幕等附属文件
+	sidecars := FindSidecars(file)
 
 	// 移动文件到失败目录
-	err := MoveFile(file, base+"/"+path.Base(file))
+	err := MoveFile(file, failPath)
 	// 检查
 	if err != nil {
 		return
 	}
+
+	// 附属文件一同移动
+	for _, sc := range sidecars {
+		_ = MoveFile(sc.File, base+"/"+filepath.Base(sc.File))
+	}
 }
 
-// MoveFile 移动文件到指定路径，并返回错误信息
+// FailPath 获取文件移动到失败目录后的路径
+//
+// file 字符串参数，传入文件路径，
+// cfg 配置信息，用以读取fail目录。
+func FailPath(file string, cfg *ConfigStruct) string {
+	return OutputPath(cfg) + "/" + cfg.Path.Fail + "/" + path.Base(file)
+}
+
+// MoveFile 移动文件到指定路径，并返回错误信息，
+// 目标文件已存在时不覆盖并返回错误，跨设备移动时将先复制并校验，再删除原文件。
 //
 // oldPath 字符串参数，传入文件原始路径，
 // newPath 字符串参数，传入文件移动路径。
 func MoveFile(oldPath, newPath string) error {
 	// 创建目录
-	err := os.MkdirAll(filepath.Dir(newPath), os.ModePerm)
+	err := MkdirAll(filepath.Dir(newPath))
 	// 检查错误
 	if err != nil {
 		return err
 	}
+	// 目标文件已存在，不覆盖
+	if Exists(newPath) && !sameFile(oldPath, newPath) {
+		return fmt.Errorf("%s: 文件已存在", newPath)
+	}
 	// 移动文件
-	return os.Rename(oldPath, newPath)
+	err = os.Rename(oldPath, newPath)
+	// 跨设备
+	if isCrossDevice(err) {
+		err = moveCrossDevice(oldPath, newPath)
+	}
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 记录到整理日志
+	journalRecord(JournalMove, oldPath, newPath)
+
+	return nil
 }
 
 // GetFileSize 获取指定文件大小，失败则返回0
@@ -54,13 +92,16 @@ func GetFileSize(file string) int64 {
 	return info.Size()
 }
 
-// WriteFile 将字节集数据写入到指定文件中，并返回错误信息
+// WriteFile 将字节集数据写入到指定文件中，并返回错误信息，
+// 记录整理日志时，已存在的文件将先备份，撤销时恢复。
 //
 // file 字符串参数，传入写入文件路径，
 // data 字节集参数，传入写入的数据。
 func WriteFile(file string, data []byte) error {
-	// 写文件
-	return ioutil.WriteFile(file, data, 0644)
+	// 写文件并记录到整理日志
+	return journalWrite(file, func() error {
+		return ioutil.WriteFile(file, data, 0644)
+	})
 }
 
 // ReadFile 读取文件
@@ -91,3 +132,19 @@ func Exists(file string) bool {
 
 	return false
 }
+
+// 检查两个路径是否指向同一个文件，如仅大小写不同的路径
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	// 检查
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	// 检查
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(ai, bi)
+}
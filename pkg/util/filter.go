@@ -0,0 +1,165 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileFilter 视频文件过滤规则，用于遍历及监控目录时筛选需要整理的视频文件
+type FileFilter struct {
+	root    string              // 遍历的根目录
+	skip    []string            // 跳过的目录，如成功及失败目录
+	exclude []string            // 排除的 glob 模式，已转换为小写
+	exts    map[string]struct{} // 视频扩展名
+	minSize int64               // 最小文件大小，单位字节
+}
+
+// NewFileFilter 通过配置信息返回视频文件过滤规则。
+//
+// root 字符串参数，传入要遍历的根目录，排除规则中的相对路径以此为准，
+// cfg 配置信息，用以读取过滤规则。
+func NewFileFilter(root string, cfg *ConfigStruct) *FileFilter {
+	// 输出目录
+	output := OutputPath(cfg)
+
+	// 排除规则
+	var exclude []string
+	for _, pattern := range cfg.Path.Exclude {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			exclude = append(exclude, strings.ToLower(filepath.ToSlash(pattern)))
+		}
+	}
+
+	return &FileFilter{
+		root: root,
+		skip: []string{
+			filepath.Join(output, cfg.Path.Success),
+			filepath.Join(output, cfg.Path.Fail),
+		},
+		exclude: exclude,
+		exts:    videoExts(cfg.Path.Exts),
+		minSize: int64(cfg.Path.MinSize) * 1024 * 1024,
+	}
+}
+
+// SkipDir 检查目录是否需要跳过
+//
+// dir 字符串参数，传入目录路径。
+func (f *FileFilter) SkipDir(dir string) bool {
+	// 成功及失败目录
+	for _, skip := range f.skip {
+		if filepath.Clean(dir) == skip {
+			return true
+		}
+	}
+
+	// 隐藏目录
+	if strings.HasPrefix(filepath.Base(dir), ".") {
+		return true
+	}
+
+	return f.excluded(dir)
+}
+
+// Match 检查文件是否为需要整理的视频文件
+//
+// file 字符串参数，传入文件路径。
+func (f *FileFilter) Match(file string) bool {
+	// 获取文件信息
+	info, err := os.Stat(file)
+	// 检查
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	// 检查所在目录
+	for dir := filepath.Dir(file); len(dir) > len(f.root); dir = filepath.Dir(dir) {
+		if f.SkipDir(dir) {
+			return false
+		}
+	}
+
+	return f.match(file, info)
+}
+
+// 检查文件本身是否符合规则
+func (f *FileFilter) match(file string, info os.FileInfo) bool {
+	// 隐藏文件
+	if strings.HasPrefix(info.Name(), ".") {
+		return false
+	}
+
+	// 扩展名
+	if _, ok := f.exts[strings.ToLower(filepath.Ext(file))]; !ok {
+		return false
+	}
+
+	// 文件大小，过滤预告片、样片等小文件
+	if info.Size() < f.minSize {
+		return false
+	}
+
+	return !f.excluded(file)
+}
+
+// 检查路径是否匹配排除规则，规则可匹配文件名称或相对于根目录的路径
+func (f *FileFilter) excluded(file string) bool {
+	// 没有排除规则
+	if len(f.exclude) == 0 {
+		return false
+	}
+
+	// 文件名称
+	name := strings.ToLower(filepath.Base(file))
+	// 相对路径
+	rel, err := filepath.Rel(f.root, file)
+	if err != nil {
+		rel = file
+	}
+	rel = strings.ToLower(filepath.ToSlash(rel))
+
+	for _, pattern := range f.exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsVideoExt 检查文件扩展名是否为视频扩展名
+//
+// file 字符串参数，传入文件路径，
+// exts 字符串数组，传入视频扩展名列表，为空时使用默认列表。
+func IsVideoExt(file string, exts []string) bool {
+	_, ok := videoExts(exts)[strings.ToLower(filepath.Ext(file))]
+
+	return ok
+}
+
+// 转换视频扩展名列表，统一为小写并以 "." 开头
+func videoExts(exts []string) map[string]struct{} {
+	// 使用默认列表
+	if len(exts) == 0 {
+		exts = DefaultVideoExts
+	}
+
+	m := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		m[ext] = struct{}{}
+	}
+
+	return m
+}
@@ -2,17 +2,15 @@ package util
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -41,31 +39,110 @@ func MakeRequest(
 	data []byte,
 	status int,
 	err error) {
-	// 构建请求客户端
+	return MakeRequestContext(context.Background(), method, uri, proxy, body, header, cookies)
+}
+
+// MakeRequestContext 创建一个携带上下文的远程请求对象，
+// 上下文被取消或超时时请求将被中断，其余参数及返回数据同 MakeRequest。
+//
+// ctx 上下文参数，传入请求所使用的上下文。
+func MakeRequestContext(
+	ctx context.Context,
+	method, uri, proxy string,
+	body io.Reader,
+	header map[string]string,
+	cookies []*http.Cookie) (
+	data []byte,
+	status int,
+	err error) {
+	// 获取共享请求客户端
 	client := createHTTPClient(proxy)
 
+	// 缓存请求内容，以便重试时重新发送
+	var payload []byte
+	if body != nil {
+		payload, err = ioutil.ReadAll(body)
+		// 检查错误
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s [Request]: %s", uri, err)
+		}
+	}
+
+	// 获取重试策略
+	retry, backoff := retryPolicy()
+
+	// 循环请求，直到成功或重试次数用尽
+	for attempt := 0; ; attempt++ {
+		// 执行请求
+		var res *http.Response
+		res, data, err = doRequest(ctx, client, method, uri, payload, header, cookies)
+
+		// 获取请求状态码
+		status = 0
+		if res != nil {
+			status = res.StatusCode
+		}
+
+		// 是否需要重试
+		if attempt >= retry || !shouldRetry(ctx, status, err) {
+			break
+		}
+
+		// 等待后重试
+		if errSleep := sleepContext(ctx, backoffDelay(backoff, attempt, res)); errSleep != nil {
+			break
+		}
+	}
+
+	// 检查错误
+	if err != nil {
+		return nil, status, fmt.Errorf("%s [Request]: %s", uri, err)
+	}
+
+	return data, status, nil
+}
+
+// 执行单次请求，返回响应对象及读取到的内容
+func doRequest(
+	ctx context.Context,
+	client *http.Client,
+	method, uri string,
+	payload []byte,
+	header map[string]string,
+	cookies []*http.Cookie) (*http.Response, []byte, error) {
+	// 请求内容
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
 	// 创建请求对象
-	req, err := createRequest(method, uri, body, header, cookies)
+	req, err := createRequest(ctx, method, uri, body, header, cookies)
 	// 检查错误
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
+	}
+
+	// 域名限速
+	if l := hostLimiter(req.URL.Hostname()); l != nil {
+		if err = l.wait(ctx); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// 执行请求
 	res, err := client.Do(req)
 	// 检查错误
 	if err != nil {
-		return nil, 0, fmt.Errorf("%s [Request]: %s", uri, err)
+		return nil, nil, err
 	}
 
-	// 获取请求状态码
-	status = res.StatusCode
 	// 读取请求内容
-	data, err = ioutil.ReadAll(res.Body)
+	data, err := ioutil.ReadAll(res.Body)
 	// 关闭请求连接
 	_ = res.Body.Close()
 
-	return data, status, err
+	return res, data, err
 }
 
 // GetResult 获取远程字节集数据，并返回字节集数据及错误信息
@@ -74,6 +151,20 @@ func MakeRequest(
 // proxy 字符串参数，传入代理地址，
 // cookies cookie数组，传入cookie信息。
 func GetResult(uri, proxy string, cookies []*http.Cookie) ([]byte, error) {
+	return GetResultContext(context.Background(), uri, proxy, cookies)
+}
+
+// GetResultContext 携带上下文获取远程字节集数据，其余参数同 GetResult，
+// 上下文携带缓存会话时，响应将在调用 CommitCache 后写入缓存。
+//
+// ctx 上下文参数，传入请求所使用的上下文。
+func GetResultContext(ctx context.Context, uri, proxy string, cookies []*http.Cookie) ([]byte, error) {
+	// 读取缓存
+	key := cacheKey(uri, cookies)
+	if data, ok := readCache(key); ok {
+		return data, nil
+	}
+
 	// 头部定义
 	header := make(map[string]string)
 	// 加入头部信息
@@ -83,7 +174,7 @@ func GetResult(uri, proxy string, cookies []*http.Cookie) ([]byte, error) {
 	header["referer"] = uri
 
 	// 执行请求
-	body, status, err := MakeRequest("GET", uri, proxy, nil, header, cookies)
+	body, status, err := MakeRequestContext(ctx, "GET", uri, proxy, nil, header, cookies)
 	// 检查错误
 	if err != nil {
 		return nil, err
@@ -91,10 +182,13 @@ func GetResult(uri, proxy string, cookies []*http.Cookie) ([]byte, error) {
 
 	// 检查状态码
 	if http.StatusBadRequest <= status {
-		err = fmt.Errorf("%s [Http Status]: %d", uri, status)
+		return body, fmt.Errorf("%s [Http Status]: %d", uri, status)
 	}
 
-	return body, err
+	// 暂存到缓存会话，刮削成功后写入缓存
+	stageCache(ctx, key, body)
+
+	return body, nil
 }
 
 // GetRoot 获取远程树结构，并返回树结构及错误信息
@@ -103,8 +197,15 @@ func GetResult(uri, proxy string, cookies []*http.Cookie) ([]byte, error) {
 // proxy 字符串参数，传入代理地址，
 // cookies cookie数组，传入cookie信息。
 func GetRoot(uri, proxy string, cookies []*http.Cookie) (*goquery.Document, error) {
+	return GetRootContext(context.Background(), uri, proxy, cookies)
+}
+
+// GetRootContext 携带上下文获取远程树结构，其余参数同 GetRoot
+//
+// ctx 上下文参数，传入请求所使用的上下文。
+func GetRootContext(ctx context.Context, uri, proxy string, cookies []*http.Cookie) (*goquery.Document, error) {
 	// 获取远程字节集数据
-	data, err := GetResult(uri, proxy, cookies)
+	data, err := GetResultContext(ctx, uri, proxy, cookies)
 	// 检查错误
 	if err != nil {
 		return nil, err
@@ -127,15 +228,22 @@ func GetRoot(uri, proxy string, cookies []*http.Cookie) (*goquery.Document, erro
 // proxy 字符串参数，代理地址，
 // needConvert 逻辑参数，是否需要将图片转换为jpg。
 func SavePhoto(uri, savePath, proxy string, needConvert bool) error {
+	return SavePhotoContext(context.Background(), uri, savePath, proxy, needConvert)
+}
+
+// SavePhotoContext 携带上下文下载远程图片到本地，其余参数同 SavePhoto
+//
+// ctx 上下文参数，传入请求所使用的上下文。
+func SavePhotoContext(ctx context.Context, uri, savePath, proxy string, needConvert bool) error {
 	// 创建路径
-	err := os.MkdirAll(filepath.Dir(savePath), os.ModePerm)
+	err := MkdirAll(filepath.Dir(savePath))
 	// 检查错误
 	if err != nil {
 		return err
 	}
 
 	// 读取远程字节集
-	body, err := GetResult(uri, proxy, nil)
+	body, err := GetResultContext(ctx, uri, proxy, nil)
 	// 检查错误
 	if err != nil {
 		return err
@@ -147,6 +255,8 @@ func SavePhoto(uri, savePath, proxy string, needConvert bool) error {
 	if length == 0 || length < 1024 {
 		return fmt.Errorf("远程图片不完整或小于1KB")
 	}
+	// 图片完整，写入缓存
+	writeCache(cacheKey(uri, nil), body)
 
 	// 保存到本地
 	err = saveFile(savePath, body, length)
@@ -171,35 +281,15 @@ func SavePhoto(uri, savePath, proxy string, needConvert bool) error {
 	return nil
 }
 
-// 创建http客户端
-func createHTTPClient(proxy string) *http.Client {
-	// 初始化
-	transport := &http.Transport{
-		/* #nosec */
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	// 如果有代理
-	if proxy != "" {
-		// 解析代理地址
-		proxyURI := func(_ *http.Request) (*url.URL, error) {
-			return url.Parse(proxy)
-		}
-		// 加入代理
-		transport.Proxy = proxyURI
-	}
-
-	// 返回客户端
-	return &http.Client{
-		Transport: transport,
-		Timeout:   60 * time.Second,
-	}
-}
-
 // 创建请求对象
-func createRequest(method, uri string, body io.Reader, header map[string]string, cookies []*http.Cookie) (*http.Request, error) {
+func createRequest(
+	ctx context.Context,
+	method, uri string,
+	body io.Reader,
+	header map[string]string,
+	cookies []*http.Cookie) (*http.Request, error) {
 	// 新建请求
-	req, err := http.NewRequest(method, uri, body)
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
 	// 检查错误
 	if err != nil {
 		return nil, fmt.Errorf("%s [Request]: %s", uri, err)
@@ -225,41 +315,36 @@ func createRequest(method, uri string, body io.Reader, header map[string]string,
 
 // 保存字节集到本地
 func saveFile(savePath string, data []byte, length int64) error {
-	// 定义错误变量
-	var err error
-
 	// 创建路径
-	_ = os.MkdirAll(path.Dir(savePath), os.ModePerm)
-
-	// 创建空文件
-	f, err := os.Create(savePath)
-	// 检查错误
-	if err != nil {
-		return err
-	}
+	_ = MkdirAll(path.Dir(savePath))
 
-	// 读取数据
-	rc := bytes.NewReader(data)
-	// 拷贝到指定路径
-	_, err = io.Copy(f, rc)
-	// 检查错误
-	if err != nil {
-		return err
-	}
-
-	// 关闭连接
-	_ = f.Close()
+	// 写文件并记录到整理日志
+	return journalWrite(savePath, func() error {
+		// 创建空文件
+		f, err := os.Create(savePath)
+		// 检查错误
+		if err != nil {
+			return err
+		}
 
-	// 获取文件大小
-	local := GetFileSize(savePath)
+		// 读取数据
+		rc := bytes.NewReader(data)
+		// 拷贝到指定路径
+		_, err = io.Copy(f, rc)
+		// 关闭连接
+		_ = f.Close()
+		// 检查错误
+		if err != nil {
+			return err
+		}
 
-	// 检查文件一致性
-	if length != local {
-		// 删除已下载文件
-		_ = os.Remove(savePath)
-		// 设置错误信息
-		err = fmt.Errorf("文件不完成, 下载失败")
-	}
+		// 检查文件一致性
+		if length != GetFileSize(savePath) {
+			// 删除已下载文件
+			_ = os.Remove(savePath)
+			return fmt.Errorf("文件不完成, 下载失败")
+		}
 
-	return err
+		return nil
+	})
 }
@@ -1,10 +1,16 @@
 package util
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
+	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,12 +50,18 @@ func ConvertJPG(sourceFile, newFile string) error {
 		SubImage(r image.Rectangle) image.Image
 	}).SubImage(image.Rect(0, 0, b.Max.X, b.Max.Y))
 
+	// 是否已存在
+	existed := Exists(newFile)
 	// 新建并打开新图片
 	cf, err := os.OpenFile(newFile, os.O_SYNC|os.O_RDWR|os.O_CREATE, 0666)
 	// 检查错误
 	if err != nil {
 		return err
 	}
+	// 新生成的文件记录到整理日志
+	if !existed {
+		journalRecord(JournalCreate, "", newFile)
+	}
 	// 关闭
 	defer cf.Close()
 
@@ -117,7 +129,7 @@ func PosterCover(srcPhoto, newPhoto string, cfg *ConfigStruct) error {
 // 腾讯云免费人脸识别
 func detectFace(photo string, cfg *ConfigStruct) (*iai.DetectFaceResponse, error) {
 	// 图片先转换为base64
-	base64, err := Base64(photo)
+	base64, err := Base64(photo, cfg.Base.Proxy)
 	// 检查错误
 	if err != nil {
 		return nil, err
@@ -199,12 +211,18 @@ func loadCover(photo string) (img image.Image, err error) {
 
 // 保存图片
 func saveCover(path string, img image.Image) error {
+	// 是否已存在
+	existed := Exists(path)
 	// 新建并打开文件
 	f, err := os.OpenFile(path, os.O_SYNC|os.O_RDWR|os.O_CREATE, 0666)
 	// 检查错误
 	if err != nil {
 		return err
 	}
+	// 新生成的文件记录到整理日志
+	if !existed {
+		journalRecord(JournalCreate, "", path)
+	}
 	// 关闭
 	defer f.Close()
 
@@ -222,3 +240,152 @@ func saveCover(path string, img image.Image) error {
 
 	return err
 }
+
+// 缩小后重新编码的 jpeg 质量
+const shrinkQuality = 90
+
+// 缩小时的最小边长，达到后不再继续缩小
+const shrinkMinDimension = 64
+
+// ImageBase64 读取本地或远程图片，按配置缩小后编码为 Base64 字符串，
+// 图片无法解码时返回错误，用于内嵌到 vsmeta 或上传至媒体库。
+//
+// file 字符串参数，传入图片路径或 http、https 远程链接，
+// proxy 字符串参数，传入读取远程链接时使用的代理地址，
+// cfg ImageStruct结构体，传入内嵌图片配置。
+func ImageBase64(file, proxy string, cfg ImageStruct) (string, error) {
+	return ImageBase64Context(context.Background(), file, proxy, cfg)
+}
+
+// ImageBase64Context 携带上下文读取图片并编码，其余参数同 ImageBase64，
+// 上下文被取消时将中断远程图片的下载。
+//
+// ctx 上下文参数，传入读取远程链接所使用的上下文。
+func ImageBase64Context(ctx context.Context, file, proxy string, cfg ImageStruct) (string, error) {
+	// 读取图片
+	data, err := readImage(ctx, file, proxy)
+	// 检查
+	if err != nil {
+		return "", err
+	}
+
+	// 检查并缩小图片
+	data, err = ShrinkImage(data, cfg)
+	// 检查
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// ShrinkImage 检查图片能否解码，并在超出配置的尺寸或大小时等比缩小，
+// 重新编码为 jpeg 返回，未超出限制时返回原始内容。
+//
+// data 字节集参数，传入图片内容，
+// cfg ImageStruct结构体，传入内嵌图片配置。
+func ShrinkImage(data []byte, cfg ImageStruct) ([]byte, error) {
+	// 图片解码
+	img, _, err := image.Decode(bytes.NewReader(data))
+	// 检查
+	if err != nil {
+		return nil, fmt.Errorf("图片无法解码: %s", err)
+	}
+
+	// 最长边
+	b := img.Bounds()
+	longest := b.Dx()
+	if b.Dy() > longest {
+		longest = b.Dy()
+	}
+
+	// 缩放比例
+	scale := 1.0
+	if cfg.MaxDimension > 0 && longest > cfg.MaxDimension {
+		scale = float64(cfg.MaxDimension) / float64(longest)
+	}
+	// 最大字节数
+	maxBytes := cfg.MaxSize * 1024
+	// 未超出限制
+	if scale == 1 && (maxBytes <= 0 || len(data) <= maxBytes) {
+		return data, nil
+	}
+
+	// 转换为 RGBA 以便缩小
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	for {
+		// 缩小后的尺寸
+		w := int(math.Max(1, math.Round(float64(b.Dx())*scale)))
+		h := int(math.Max(1, math.Round(float64(b.Dy())*scale)))
+
+		// 重新编码
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, resizeImage(src, w, h), &jpeg.Options{Quality: shrinkQuality})
+		// 检查
+		if err != nil {
+			return nil, err
+		}
+
+		// 大小符合或已无法继续缩小
+		if maxBytes <= 0 || buf.Len() <= maxBytes || w <= shrinkMinDimension || h <= shrinkMinDimension {
+			return buf.Bytes(), nil
+		}
+
+		// 按大小比例继续缩小
+		scale *= math.Sqrt(float64(maxBytes)/float64(buf.Len())) * 0.95
+	}
+}
+
+// 读取本地或远程图片，只有 http、https 链接才从网络读取，本地文件读取失败时直接返回错误
+func readImage(ctx context.Context, file, proxy string) ([]byte, error) {
+	// 远程链接
+	if isRemote(file) {
+		return readURI(ctx, file, proxy)
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+// 使用区域平均缩小图片
+func resizeImage(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < h; y++ {
+		// 对应的原图行范围
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			// 对应的原图列范围
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// 累加区域内的像素
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				p := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(p); i += 4 {
+					sum[0] += uint64(p[i])
+					sum[1] += uint64(p[i+1])
+					sum[2] += uint64(p[i+2])
+					sum[3] += uint64(p[i+3])
+				}
+			}
+
+			// 取平均值
+			n := uint64((x1 - x0) * (y1 - y0))
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
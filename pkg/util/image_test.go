@@ -0,0 +1,137 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// 生成测试用 png 图片，noise 为 true 时填充随机像素
+func testPNG(t *testing.T, w, h int, noise bool) []byte {
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{uint8(x), uint8(y), 128, 255}
+			if noise {
+				c = color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestBase64Reader(t *testing.T) {
+	// 超出原有 500000 字节缓冲区的内容
+	data := bytes.Repeat([]byte("AVMeta"), 200000)
+
+	got, err := Base64Reader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Base64Reader() error = %v", err)
+	}
+	if got != base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("Base64Reader() 长度 = %d, 内容不一致", len(got))
+	}
+}
+
+func TestShrinkImage(t *testing.T) {
+	small := testPNG(t, 400, 300, false)
+	noise := testPNG(t, 400, 300, true)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		cfg    ImageStruct
+		same   bool
+		width  int
+		height int
+		max    int
+	}{
+		{name: "unlimited", data: small, same: true, width: 400, height: 300},
+		{name: "within", data: small, cfg: ImageStruct{MaxDimension: 400, MaxSize: 1024}, same: true, width: 400, height: 300},
+		{name: "dimension", data: small, cfg: ImageStruct{MaxDimension: 100}, width: 100, height: 75},
+		{name: "size", data: noise, cfg: ImageStruct{MaxSize: 20}, max: 20 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShrinkImage(tt.data, tt.cfg)
+			if err != nil {
+				t.Fatalf("ShrinkImage() error = %v", err)
+			}
+			if tt.same != bytes.Equal(got, tt.data) {
+				t.Errorf("ShrinkImage() 返回原始内容 = %t, want %t", !tt.same, tt.same)
+			}
+
+			// 检查能否解码
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("DecodeConfig() error = %v", err)
+			}
+			if tt.width > 0 && (cfg.Width != tt.width || cfg.Height != tt.height) {
+				t.Errorf("尺寸 = %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.width, tt.height)
+			}
+			if tt.max > 0 && len(got) > tt.max {
+				t.Errorf("大小 = %d, want <= %d", len(got), tt.max)
+			}
+		})
+	}
+
+	// 无法解码的图片
+	if _, err := ShrinkImage([]byte("not an image"), ImageStruct{}); err == nil {
+		t.Error("ShrinkImage() 无效图片未返回错误")
+	}
+}
+
+func TestImageBase64Remote(t *testing.T) {
+	data := testPNG(t, 40, 30, false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/poster.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	// 通过共享请求客户端读取远程图片
+	got, err := ImageBase64(srv.URL+"/poster.png", "", ImageStruct{})
+	if err != nil {
+		t.Fatalf("ImageBase64() error = %v", err)
+	}
+	if got != base64.StdEncoding.EncodeToString(data) {
+		t.Error("ImageBase64() 内容不一致")
+	}
+
+	// 状态码错误
+	if _, err = ImageBase64(srv.URL+"/missing.png", "", ImageStruct{}); err == nil {
+		t.Error("ImageBase64() 404 未返回错误")
+	}
+
+	// 上下文已取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = ImageBase64Context(ctx, srv.URL+"/poster.png?cancel", "", ImageStruct{}); err == nil {
+		t.Error("ImageBase64Context() 上下文取消后未返回错误")
+	}
+
+	// 本地文件不存在时返回本地错误，不作为远程链接读取
+	if _, err = ImageBase64("/nonexistent/poster.jpg", "", ImageStruct{}); !os.IsNotExist(err) {
+		t.Errorf("ImageBase64() error = %v, want 文件不存在", err)
+	}
+}
@@ -0,0 +1,243 @@
+package util
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 整理结果
+const (
+	IndexSuccess = "success" // 整理成功
+	IndexFail    = "fail"    // 整理失败
+)
+
+// 文件在整理索引中的状态
+const (
+	IndexNew       = "new"       // 未整理过的文件
+	IndexChanged   = "changed"   // 整理过但大小或修改时间已变化的文件
+	IndexFailed    = "failed"    // 上次整理失败的文件
+	IndexUnchanged = "unchanged" // 已整理成功且未变化的文件
+)
+
+// IndexEntry 整理索引条目，记录一个文件最近一次的整理结果。
+type IndexEntry struct {
+	File    string    `json:"file"`             // 文件原始路径
+	Size    int64     `json:"size"`             // 文件大小
+	ModTime int64     `json:"mtime"`            // 文件修改时间，纳秒
+	Code    string    `json:"code,omitempty"`   // 番号
+	Source  string    `json:"source,omitempty"` // 刮削来源
+	Result  string    `json:"result"`           // 整理结果，参见常量定义
+	Dest    string    `json:"dest,omitempty"`   // 整理后的目录
+	Target  string    `json:"target,omitempty"` // 整理后的文件路径，失败时为失败目录中的路径
+	Error   string    `json:"error,omitempty"`  // 失败原因
+	Time    time.Time `json:"time"`             // 整理时间
+}
+
+// Index 整理索引，以文件名称、大小及修改时间为键记录每个文件的整理结果，
+// 文件被移动到成功或失败目录后仍可识别，
+// 再次整理时跳过已整理成功的文件。
+type Index struct {
+	mu      sync.Mutex
+	file    string                 // 索引文件路径
+	entries map[string]*IndexEntry // 索引条目，键为文件名称、大小及修改时间
+	paths   map[string]*IndexEntry // 索引条目，键为文件原始路径
+}
+
+// IndexPath 获取整理索引文件路径，位于程序执行路径下
+func IndexPath() string {
+	return GetRunPath() + "/index.json"
+}
+
+// OpenIndex 读取整理索引，索引文件不存在时返回空索引。
+//
+// file 字符串参数，传入索引文件路径。
+func OpenIndex(file string) (*Index, error) {
+	idx := &Index{file: file, entries: make(map[string]*IndexEntry), paths: make(map[string]*IndexEntry)}
+
+	// 读取文件
+	b, err := ioutil.ReadFile(file)
+	// 检查
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析条目
+	var entries []*IndexEntry
+	if err = json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		idx.add(entry)
+	}
+
+	return idx, nil
+}
+
+// Status 获取文件在索引中的状态及上次整理记录，
+// 文件不存在时视为未整理过的文件。
+//
+// file 字符串参数，传入文件路径。
+func (idx *Index) Status(file string) (string, *IndexEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// 获取文件信息
+	info, err := os.Stat(file)
+	if err != nil {
+		return IndexNew, nil
+	}
+
+	// 按文件名称、大小及修改时间查找，文件移动后仍可找到
+	entry, ok := idx.entries[indexID(filepath.Base(file), info.Size(), info.ModTime().UnixNano())]
+	if !ok {
+		// 同一路径的文件已变化
+		if entry, ok = idx.paths[indexKey(file)]; ok {
+			return IndexChanged, entry
+		}
+		return IndexNew, nil
+	}
+
+	// 上次整理失败
+	if entry.Result != IndexSuccess {
+		return IndexFailed, entry
+	}
+
+	return IndexUnchanged, entry
+}
+
+// Put 记录文件的整理结果，文件信息需在整理前获取，
+// 同一文件的旧记录将被替换。
+//
+// entry IndexEntry对象，传入整理结果。
+func (idx *Index) Put(entry IndexEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// 记录时间
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.File = indexKey(entry.File)
+
+	// 删除同一路径的旧记录
+	if old, ok := idx.paths[entry.File]; ok {
+		idx.remove(old)
+	}
+	// 删除同一文件的旧记录
+	if old, ok := idx.entries[entry.id()]; ok {
+		idx.remove(old)
+	}
+
+	idx.add(&entry)
+}
+
+// Prune 删除原始路径及整理后路径均已不存在的条目，并返回删除数量
+func (idx *Index) Prune() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	count := 0
+	for _, entry := range idx.entries {
+		if Exists(entry.File) || (entry.Target != "" && Exists(entry.Target)) {
+			continue
+		}
+		idx.remove(entry)
+		count++
+	}
+
+	return count
+}
+
+// 加入条目
+func (idx *Index) add(entry *IndexEntry) {
+	idx.entries[entry.id()] = entry
+	idx.paths[entry.File] = entry
+}
+
+// 删除条目
+func (idx *Index) remove(entry *IndexEntry) {
+	delete(idx.entries, entry.id())
+	if idx.paths[entry.File] == entry {
+		delete(idx.paths, entry.File)
+	}
+}
+
+// 条目键名
+func (entry *IndexEntry) id() string {
+	return indexID(filepath.Base(entry.File), entry.Size, entry.ModTime)
+}
+
+// 索引键名，由文件名称、大小及修改时间组成
+func indexID(name string, size, modTime int64) string {
+	return strings.Join([]string{name, strconv.FormatInt(size, 10), strconv.FormatInt(modTime, 10)}, "|")
+}
+
+// Save 保存整理索引，保存前删除文件已不存在的条目，
+// 先写入临时文件再替换，避免写入中断导致索引损坏
+func (idx *Index) Save() error {
+	// 删除失效条目
+	idx.Prune()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// 按路径排序，便于查看及比较
+	entries := make([]*IndexEntry, 0, len(idx.entries))
+	for _, entry := range idx.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].File < entries[j].File
+	})
+
+	// 转换为json
+	b, err := json.MarshalIndent(entries, "", "  ")
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 写入临时文件
+	tmp := idx.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, idx.file)
+}
+
+// NewIndexEntry 获取文件当前的大小及修改时间，返回未填写整理结果的索引条目
+//
+// file 字符串参数，传入文件路径。
+func NewIndexEntry(file string) IndexEntry {
+	entry := IndexEntry{File: file}
+
+	// 获取文件信息
+	if info, err := os.Stat(file); err == nil {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime().UnixNano()
+	}
+
+	return entry
+}
+
+// 路径键名，统一为绝对路径
+func indexKey(file string) string {
+	// 转换为绝对路径
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+
+	return abs
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 创建测试文件
+	create := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := MkdirAll(filepath.Dir(file)); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return file
+	}
+	// 检查状态
+	status := func(idx *Index, file, want string) {
+		t.Helper()
+		if got, _ := idx.Status(file); got != want {
+			t.Errorf("Status(%s) = %s, want %s", filepath.Base(file), got, want)
+		}
+	}
+
+	ok := create("input/SSIS-001.mp4", "ok")
+	failed := create("input/SSIS-002.mp4", "failed")
+	changed := create("input/SSIS-003.mp4", "changed")
+
+	idx, err := OpenIndex(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	status(idx, ok, IndexNew)
+
+	// 记录整理结果
+	success := filepath.Join(dir, "success/SSIS-001/SSIS-001.mp4")
+	fail := filepath.Join(dir, "fail/SSIS-002.mp4")
+	for _, e := range []struct {
+		file, result, target string
+	}{
+		{ok, IndexSuccess, success},
+		{failed, IndexFail, fail},
+		{changed, IndexSuccess, ""},
+	} {
+		entry := NewIndexEntry(e.file)
+		entry.Result, entry.Target = e.result, e.target
+		idx.Put(entry)
+	}
+
+	// 按整理方式移动文件，修改时间保持不变
+	if err = MkdirAll(filepath.Dir(success)); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err = os.Rename(ok, success); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err = MkdirAll(filepath.Dir(fail)); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err = os.Rename(failed, fail); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	// 修改文件
+	create("input/SSIS-003.mp4", "changed again")
+	_ = os.Chtimes(changed, time.Now(), time.Now().Add(time.Hour))
+
+	status(idx, success, IndexUnchanged)
+	status(idx, fail, IndexFailed)
+	status(idx, changed, IndexChanged)
+
+	// 失败目录中的文件再次整理成功，替换旧记录
+	entry := NewIndexEntry(fail)
+	entry.Result = IndexSuccess
+	idx.Put(entry)
+	status(idx, fail, IndexUnchanged)
+
+	// 删除文件后保存，失效条目被删除
+	if err = os.Remove(fail); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err = idx.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// 重新读取
+	idx, err = OpenIndex(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	if len(idx.entries) != 2 {
+		t.Errorf("条目数量 = %d, want 2", len(idx.entries))
+	}
+	status(idx, success, IndexUnchanged)
+	status(idx, changed, IndexChanged)
+	status(idx, create("fail/SSIS-002.mp4", "other"), IndexNew)
+}
@@ -0,0 +1,444 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 整理日志操作类型
+const (
+	JournalMove    = "move"    // 移动文件
+	JournalMkdir   = "mkdir"   // 创建目录
+	JournalCreate  = "create"  // 生成文件
+	JournalReplace = "replace" // 覆盖文件，原文件备份在整理日志目录中
+
+	// 整理日志文件后缀
+	journalExt = ".jsonl"
+	// 已撤销的整理日志文件后缀
+	journalUndoneExt = ".undone"
+	// 被覆盖文件的备份目录后缀
+	journalBackupExt = ".backup"
+)
+
+// ErrDirKept 撤销时目录中还有其他文件，目录被保留，不视为撤销失败
+var ErrDirKept = errors.New("目录非空, 已保留")
+
+// JournalEntry 整理日志条目，记录一次文件操作。
+type JournalEntry struct {
+	Op   string    `json:"op"`            // 操作类型
+	Src  string    `json:"src,omitempty"` // 原始路径，移动操作为文件原位置，覆盖操作为备份位置
+	Dst  string    `json:"dst"`           // 目标路径
+	Time time.Time `json:"time"`          // 操作时间
+}
+
+// Journal 整理日志对象，
+// 每次整理均生成一个以运行编号命名的日志文件，每行一个 JSON 条目。
+type Journal struct {
+	mu      sync.Mutex
+	id      string   // 运行编号
+	f       *os.File // 日志文件
+	count   int      // 已记录条目数
+	backups int      // 已备份文件数
+}
+
+// 当前整理日志
+var journal = struct {
+	sync.Mutex
+	j *Journal
+}{}
+
+// JournalDir 获取整理日志目录，位于程序执行路径下的 journal 文件夹
+func JournalDir() string {
+	return GetRunPath() + "/journal"
+}
+
+// NewJournal 创建一个新的整理日志，并以当前时间作为运行编号
+func NewJournal() (*Journal, error) {
+	// 创建目录
+	if err := os.MkdirAll(JournalDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// 运行编号
+	id := time.Now().Format("20060102-150405")
+	// 同一秒内多次运行则增加序号
+	for i := 2; Exists(journalPath(id)) || Exists(journalPath(id)+journalUndoneExt); i++ {
+		id = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), i)
+	}
+
+	// 创建文件
+	f, err := os.OpenFile(journalPath(id), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{id: id, f: f}, nil
+}
+
+// ID 返回运行编号
+func (j *Journal) ID() string {
+	return j.id
+}
+
+// Record 记录一次文件操作，写入后立即同步到磁盘，
+// 以保证程序异常退出时已完成的操作依然可被撤销。
+//
+// op 字符串参数，传入操作类型，参见常量定义，
+// src 字符串参数，传入原始路径，
+// dst 字符串参数，传入目标路径。
+func (j *Journal) Record(op, src, dst string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// 转换为json
+	b, err := json.Marshal(JournalEntry{Op: op, Src: src, Dst: dst, Time: time.Now()})
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 写入
+	if _, err = j.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	j.count++
+
+	return j.f.Sync()
+}
+
+// Backup 将即将被覆盖的文件备份到整理日志的备份目录，并返回备份路径
+//
+// file 字符串参数，传入要备份的文件路径。
+func (j *Journal) Backup(file string) (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// 创建目录
+	dir := journalPath(j.id) + journalBackupExt
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	// 备份路径，加上序号避免重名
+	j.backups++
+	backup := filepath.Join(dir, fmt.Sprintf("%d-%s", j.backups, filepath.Base(file)))
+
+	// 读取原文件
+	data, err := ioutil.ReadFile(file)
+	// 检查
+	if err != nil {
+		return "", err
+	}
+
+	return backup, ioutil.WriteFile(backup, data, 0644)
+}
+
+// Close 关闭整理日志，没有任何记录的日志文件将被删除
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// 关闭文件
+	err := j.f.Close()
+	// 是否为空
+	if j.count == 0 {
+		_ = os.RemoveAll(journalPath(j.id) + journalBackupExt)
+		return os.Remove(journalPath(j.id))
+	}
+
+	return err
+}
+
+// SetJournal 设置当前整理日志，
+// 设置后 MoveFile、WriteFile、MkdirAll 及图片保存操作均将被记录，传入 nil 则停止记录。
+//
+// j Journal对象，传入整理日志。
+func SetJournal(j *Journal) {
+	journal.Lock()
+	defer journal.Unlock()
+
+	journal.j = j
+}
+
+// Journals 返回所有未撤销的运行编号，按时间由旧到新排序
+func Journals() ([]string, error) {
+	// 读取目录
+	files, err := ioutil.ReadDir(JournalDir())
+	// 检查
+	if err != nil {
+		// 目录不存在
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// 运行编号列表
+	var ids []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), journalExt) {
+			ids = append(ids, strings.TrimSuffix(f.Name(), journalExt))
+		}
+	}
+	// 排序
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// ReadJournal 读取指定运行编号的整理日志条目
+//
+// id 字符串参数，传入运行编号。
+func ReadJournal(id string) ([]JournalEntry, error) {
+	// 打开文件
+	f, err := os.Open(journalPath(id))
+	// 检查
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: 整理日志不存在或已撤销", id)
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	// 条目列表
+	var entries []JournalEntry
+	// 逐行读取
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 忽略空行
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// 解析
+		var entry JournalEntry
+		if err = json.Unmarshal([]byte(line), &entry); err != nil {
+			// 最后一行可能因异常退出而不完整
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// UndoJournal 撤销指定运行编号的整理操作，
+// 按记录的相反顺序将文件移回原位、删除生成的文件及空目录，
+// 全部完成后该日志将被标记为已撤销，仍有其他文件的目录将被保留，回调中传入 ErrDirKept。
+//
+// id 字符串参数，传入运行编号，
+// fn 回调函数，每处理一个条目调用一次，传入条目及处理错误。
+func UndoJournal(id string, fn func(entry JournalEntry, err error)) error {
+	// 读取条目
+	entries, err := ReadJournal(id)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 失败数量
+	failed := 0
+	// 倒序处理
+	for i := len(entries) - 1; i >= 0; i-- {
+		// 撤销，保留非空目录不视为失败
+		err := undoEntry(entries[i])
+		if err != nil && !errors.Is(err, ErrDirKept) {
+			failed++
+		}
+		// 回调
+		if fn != nil {
+			fn(entries[i], err)
+		}
+	}
+
+	// 有失败则保留日志，以便处理后再次撤销
+	if failed > 0 {
+		return fmt.Errorf("%s: %d 项操作撤销失败", id, failed)
+	}
+
+	// 删除备份目录
+	_ = os.RemoveAll(journalPath(id) + journalBackupExt)
+
+	// 标记为已撤销
+	return os.Rename(journalPath(id), journalPath(id)+journalUndoneExt)
+}
+
+// 撤销一个条目
+func undoEntry(entry JournalEntry) error {
+	switch entry.Op {
+	case JournalMove:
+		// 文件已不在目标位置
+		if !Exists(entry.Dst) {
+			// 已被移回则视为成功
+			if Exists(entry.Src) {
+				return nil
+			}
+
+			return fmt.Errorf("%s: 文件不存在", entry.Dst)
+		}
+		// 原位置已存在同名文件
+		if Exists(entry.Src) {
+			return fmt.Errorf("%s: 原位置已存在同名文件", entry.Src)
+		}
+
+		return MoveFile(entry.Dst, entry.Src)
+	case JournalReplace:
+		// 备份已恢复则视为成功
+		if !Exists(entry.Src) {
+			return nil
+		}
+
+		return restoreBackup(entry.Src, entry.Dst)
+	case JournalCreate:
+		// 删除生成的文件
+		if err := os.Remove(entry.Dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	case JournalMkdir:
+		// 读取目录
+		files, err := ioutil.ReadDir(entry.Dst)
+		// 检查
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+		// 目录中还有其他文件则保留
+		if len(files) > 0 {
+			return fmt.Errorf("%s: %w", entry.Dst, ErrDirKept)
+		}
+
+		return os.Remove(entry.Dst)
+	}
+
+	return fmt.Errorf("不支持的操作类型: %s", entry.Op)
+}
+
+// 使用备份覆盖文件并删除备份
+func restoreBackup(backup, file string) error {
+	// 读取备份
+	data, err := ioutil.ReadFile(backup)
+	// 检查
+	if err != nil {
+		return err
+	}
+	// 恢复
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Remove(backup)
+}
+
+// 获取整理日志文件路径
+func journalPath(id string) string {
+	return filepath.Join(JournalDir(), id+journalExt)
+}
+
+// 记录文件操作到当前整理日志
+func journalRecord(op, src, dst string) {
+	journal.Lock()
+	j := journal.j
+	journal.Unlock()
+
+	// 是否需要记录
+	if j == nil {
+		return
+	}
+
+	// 转换为绝对路径
+	if src != "" {
+		src, _ = filepath.Abs(src)
+	}
+	dst, _ = filepath.Abs(dst)
+
+	_ = j.Record(op, src, dst)
+}
+
+// 写入文件并记录到当前整理日志，新文件记录为生成操作，
+// 已存在的文件先备份再记录为覆盖操作，写入失败时恢复备份。
+//
+// file 字符串参数，传入文件路径，
+// write 回调函数，传入实际的写入操作。
+func journalWrite(file string, write func() error) error {
+	journal.Lock()
+	j := journal.j
+	journal.Unlock()
+
+	// 是否已存在
+	existed := Exists(file)
+	// 备份已存在的文件
+	var backup string
+	if existed && j != nil {
+		var err error
+		backup, err = j.Backup(file)
+		// 检查
+		if err != nil {
+			return fmt.Errorf("%s: 备份失败, %s", file, err)
+		}
+	}
+
+	// 写入
+	if err := write(); err != nil {
+		if backup != "" {
+			_ = restoreBackup(backup, file)
+		}
+		return err
+	}
+
+	// 记录
+	switch {
+	case !existed:
+		journalRecord(JournalCreate, "", file)
+	case backup != "":
+		journalRecord(JournalReplace, backup, file)
+	}
+
+	return nil
+}
+
+// MkdirAll 创建目录及其所有上级目录，
+// 新创建的目录将被记录到当前整理日志中。
+//
+// dir 字符串参数，传入目录路径。
+func MkdirAll(dir string) error {
+	// 查找需要创建的目录，由外到内
+	var created []string
+	for d := filepath.Clean(dir); !Exists(d); d = filepath.Dir(d) {
+		created = append([]string{d}, created...)
+		// 已到根目录
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	// 创建目录
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// 记录
+	for _, d := range created {
+		journalRecord(JournalMkdir, "", d)
+	}
+
+	return nil
+}
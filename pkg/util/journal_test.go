@@ -0,0 +1,108 @@
+package util
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalUndo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 读取文件内容
+	read := func(file string) string {
+		t.Helper()
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		return string(b)
+	}
+
+	video := filepath.Join(dir, "input/SSIS-001.mp4")
+	target := filepath.Join(dir, "success/SSIS-001/SSIS-001.mp4")
+	nfo := filepath.Join(dir, "success/SSIS-001/SSIS-001.nfo")
+	for file, content := range map[string]string{video: "video", target: "other", nfo: "old nfo"} {
+		if err = MkdirAll(filepath.Dir(file)); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	j, err := NewJournal()
+	if err != nil {
+		t.Fatalf("NewJournal() error = %v", err)
+	}
+	SetJournal(j)
+
+	// 目标已存在时不覆盖
+	if err = MoveFile(video, target); err == nil {
+		t.Error("MoveFile() 覆盖了已存在的文件")
+	}
+	if read(target) != "other" || read(video) != "video" {
+		t.Error("MoveFile() 失败后文件被修改")
+	}
+
+	// 移动到新位置，覆盖 nfo 并生成图片
+	moved := filepath.Join(dir, "success/SSIS-001/SSIS-001-C.mp4")
+	fanart := filepath.Join(dir, "success/SSIS-001/fanart.jpg")
+	if err = MoveFile(video, moved); err != nil {
+		t.Fatalf("MoveFile() error = %v", err)
+	}
+	if err = WriteFile(nfo, []byte("new nfo")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err = WriteFile(fanart, []byte("fanart")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// 新建的目录中之后又放入了其他文件
+	actor := filepath.Join(dir, "actor")
+	if err = MkdirAll(actor); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	SetJournal(nil)
+	if err = j.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(actor, "later.jpg"), []byte("later"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// 撤销，非空目录被保留且不视为失败
+	kept := 0
+	if err = UndoJournal(j.ID(), func(entry JournalEntry, err error) {
+		if errors.Is(err, ErrDirKept) {
+			kept++
+		}
+	}); err != nil {
+		t.Fatalf("UndoJournal() error = %v", err)
+	}
+	if kept != 1 || !Exists(actor) {
+		t.Errorf("保留目录数量 = %d, want 1", kept)
+	}
+	if Exists(journalPath(j.ID())) {
+		t.Error("整理日志未标记为已撤销")
+	}
+	if read(video) != "video" || Exists(moved) {
+		t.Error("视频未移回原位")
+	}
+	if got := read(nfo); got != "old nfo" {
+		t.Errorf("nfo = %q, want %q", got, "old nfo")
+	}
+	if Exists(fanart) {
+		t.Error("生成的图片未被删除")
+	}
+	if Exists(journalPath(j.ID()) + journalBackupExt) {
+		t.Error("备份目录未被删除")
+	}
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 数字分段标识，如 -cd1、_part2、.pt3、 disc1
+	partNumberRegexp = regexp.MustCompile(`^(.+?)[-_ .](?:cd|part|pt|disc|disk)[-_ .]?(\d{1,2})$`)
+	// 字母分段标识，如 -a、-b，需同目录下存在从 -a 开始连续的分段才视为分段
+	partLetterRegexp = regexp.MustCompile(`^(.+?)[-_ .]([a-z])$`)
+)
+
+// Video 待整理的视频，同一影片的多个分段文件合并为一个视频
+type Video struct {
+	Name  string      // 去除分段标识后的文件路径，用于提取番号
+	Parts []VideoPart // 分段文件列表，按分段序号排序，未分段时只有一个序号为 0 的文件
+}
+
+// VideoPart 视频分段文件
+type VideoPart struct {
+	File  string // 文件路径
+	Index int    // 分段序号，从 1 开始，0 为未分段
+}
+
+// NewVideo 返回只包含一个未分段文件的视频
+//
+// file 字符串参数，传入视频文件路径。
+func NewVideo(file string) Video {
+	return Video{Name: file, Parts: []VideoPart{{File: file}}}
+}
+
+// Files 返回视频的所有分段文件路径
+func (v Video) Files() []string {
+	// 文件列表
+	files := make([]string, 0, len(v.Parts))
+	for _, p := range v.Parts {
+		files = append(files, p.File)
+	}
+
+	return files
+}
+
+// PartSuffix 获取分段在文件名中的统一标识，如 -cd1，未分段时返回空
+//
+// index 整数参数，传入分段序号。
+func PartSuffix(index int) string {
+	// 未分段
+	if index <= 0 {
+		return ""
+	}
+
+	return "-cd" + strconv.Itoa(index)
+}
+
+// GroupVideos 识别文件名中的分段标识（cd1、part1、-A/-B 等），
+// 将同一目录下同名的分段文件合并为一个视频，返回顺序与文件首次出现的顺序一致。
+//
+// files 字符串数组，传入视频文件路径列表。
+func GroupVideos(files []string) []Video {
+	// 分组信息
+	type group struct {
+		name    string      // 去除分段标识后的路径
+		parts   []VideoPart // 分段文件
+		letters bool        // 是否为字母分段
+	}
+
+	// 分组列表及索引
+	var groups []*group
+	index := make(map[string]*group)
+
+	// 加入分组
+	add := func(key, name string, part VideoPart, letters bool) {
+		g, ok := index[key]
+		if !ok {
+			g = &group{name: name, letters: letters}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.parts = append(g.parts, part)
+	}
+
+	// 循环文件
+	for _, file := range files {
+		// 拆分路径
+		dir, name := filepath.Split(file)
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		lower := strings.ToLower(base)
+
+		// 数字分段
+		if m := partNumberRegexp.FindStringSubmatch(lower); len(m) > 0 {
+			n, _ := strconv.Atoi(m[2])
+			add("n:"+strings.ToLower(dir)+m[1], dir+base[:len(m[1])]+ext, VideoPart{File: file, Index: n}, false)
+			continue
+		}
+
+		// 字母分段
+		if m := partLetterRegexp.FindStringSubmatch(lower); len(m) > 0 {
+			n := int(m[2][0]-'a') + 1
+			add("l:"+strings.ToLower(dir)+m[1], dir+base[:len(m[1])]+ext, VideoPart{File: file, Index: n}, true)
+			continue
+		}
+
+		// 未分段
+		add("f:"+file, file, VideoPart{File: file}, false)
+	}
+
+	// 整理结果
+	var videos []Video
+	for _, g := range groups {
+		// 按分段序号排序
+		sort.SliceStable(g.parts, func(i, j int) bool {
+			return g.parts[i].Index < g.parts[j].Index
+		})
+
+		// 字母分段需从 -a 开始连续且至少两个分段，其余视为普通文件，
+		// 避免将 -c（中文字幕）、-u（无码破解）等版本标识误认为分段
+		if g.letters {
+			n := letterRun(g.parts)
+			if n < 2 {
+				n = 0
+			} else {
+				videos = append(videos, Video{Name: g.name, Parts: g.parts[:n]})
+			}
+			for _, p := range g.parts[n:] {
+				videos = append(videos, NewVideo(p.File))
+			}
+			continue
+		}
+
+		videos = append(videos, Video{Name: g.name, Parts: g.parts})
+	}
+
+	return videos
+}
+
+// 获取从 -a 开始连续的字母分段数量，parts 需已按序号排序
+func letterRun(parts []VideoPart) int {
+	for i, p := range parts {
+		if p.Index != i+1 {
+			return i
+		}
+	}
+
+	return len(parts)
+}
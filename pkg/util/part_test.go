@@ -0,0 +1,63 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupVideos(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []Video
+	}{
+		{
+			name:  "number",
+			files: []string{"/in/ABP-123-cd2.mp4", "/in/ABP-123-cd1.mp4"},
+			want: []Video{{Name: "/in/ABP-123.mp4", Parts: []VideoPart{
+				{File: "/in/ABP-123-cd1.mp4", Index: 1}, {File: "/in/ABP-123-cd2.mp4", Index: 2},
+			}}},
+		},
+		{
+			name:  "letter",
+			files: []string{"/in/ABP-123-A.mp4", "/in/ABP-123-B.mp4", "/in/ABP-123-C.mp4"},
+			want: []Video{{Name: "/in/ABP-123.mp4", Parts: []VideoPart{
+				{File: "/in/ABP-123-A.mp4", Index: 1}, {File: "/in/ABP-123-B.mp4", Index: 2}, {File: "/in/ABP-123-C.mp4", Index: 3},
+			}}},
+		},
+		{
+			// 无码破解版本不是分段
+			name:  "uncensored",
+			files: []string{"/in/ABP-123-A.mp4", "/in/ABP-123-U.mp4"},
+			want:  []Video{NewVideo("/in/ABP-123-A.mp4"), NewVideo("/in/ABP-123-U.mp4")},
+		},
+		{
+			// 中文字幕版本不是分段
+			name:  "subtitle",
+			files: []string{"/in/ABP-123-A.mp4", "/in/ABP-123-C.mp4"},
+			want:  []Video{NewVideo("/in/ABP-123-A.mp4"), NewVideo("/in/ABP-123-C.mp4")},
+		},
+		{
+			// 连续分段之外的版本单独整理
+			name:  "mixed",
+			files: []string{"/in/ABP-123-A.mp4", "/in/ABP-123-B.mp4", "/in/ABP-123-U.mp4"},
+			want: []Video{
+				{Name: "/in/ABP-123.mp4", Parts: []VideoPart{{File: "/in/ABP-123-A.mp4", Index: 1}, {File: "/in/ABP-123-B.mp4", Index: 2}}},
+				NewVideo("/in/ABP-123-U.mp4"),
+			},
+		},
+		{
+			name:  "single",
+			files: []string{"/in/ABP-123-C.mp4"},
+			want:  []Video{NewVideo("/in/ABP-123-C.mp4")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupVideos(tt.files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupVideos() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
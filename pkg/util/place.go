@@ -0,0 +1,328 @@
+package util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 整理方式
+const (
+	ModeMove     = "move"     // 移动文件
+	ModeCopy     = "copy"     // 复制文件，保留原文件
+	ModeHardlink = "hardlink" // 创建硬链接，跨设备时改为复制
+	ModeSymlink  = "symlink"  // 创建指向原文件的符号链接
+
+	// 进度回调间隔
+	progressInterval = time.Second
+)
+
+// ProgressFunc 文件复制进度回调函数
+//
+// file 字符串参数，传入正在复制的文件路径，
+// done 整数参数，传入已复制的字节数，
+// total 整数参数，传入文件总字节数。
+type ProgressFunc func(file string, done, total int64)
+
+// 复制进度回调
+var progress = struct {
+	sync.Mutex
+	fn ProgressFunc
+}{}
+
+// SetProgress 设置文件复制进度回调，
+// 复制过程中每秒回调一次，复制完成时再回调一次，传入 nil 则不回调。
+//
+// fn ProgressFunc回调函数，传入进度回调。
+func SetProgress(fn ProgressFunc) {
+	progress.Lock()
+	defer progress.Unlock()
+
+	progress.fn = fn
+}
+
+// CheckMode 检查整理方式是否受支持
+//
+// mode 字符串参数，传入整理方式。
+func CheckMode(mode string) error {
+	switch mode {
+	case "", ModeMove, ModeCopy, ModeHardlink, ModeSymlink:
+		return nil
+	}
+
+	return fmt.Errorf("不支持的整理方式: %s, 可选: move, copy, hardlink, symlink", mode)
+}
+
+// PlaceFile 按整理方式将文件放置到指定路径，并返回错误信息
+//
+// oldPath 字符串参数，传入文件原始路径，
+// newPath 字符串参数，传入文件放置路径，
+// mode 字符串参数，传入整理方式，参见常量定义，为空则移动文件。
+func PlaceFile(oldPath, newPath, mode string) error {
+	switch mode {
+	case "", ModeMove:
+		return MoveFile(oldPath, newPath)
+	case ModeCopy:
+		return CopyFile(oldPath, newPath)
+	case ModeHardlink:
+		return LinkFile(oldPath, newPath)
+	case ModeSymlink:
+		return SymlinkFile(oldPath, newPath)
+	}
+
+	return CheckMode(mode)
+}
+
+// UnplaceFile 撤销 PlaceFile 的放置操作，并返回错误信息，
+// 移动方式下将文件移回原位，其他方式下删除生成的文件。
+//
+// oldPath 字符串参数，传入文件原始路径，
+// newPath 字符串参数，传入文件放置路径，
+// mode 字符串参数，传入放置时使用的整理方式。
+func UnplaceFile(oldPath, newPath, mode string) error {
+	if mode == "" || mode == ModeMove {
+		return MoveFile(newPath, oldPath)
+	}
+
+	return os.Remove(newPath)
+}
+
+// CopyFile 复制文件到指定路径并校验，并返回错误信息
+//
+// oldPath 字符串参数，传入文件原始路径，
+// newPath 字符串参数，传入文件复制路径。
+func CopyFile(oldPath, newPath string) error {
+	// 创建目录
+	err := MkdirAll(filepath.Dir(newPath))
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 复制
+	err = copyVerify(oldPath, newPath)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 记录到整理日志
+	journalRecord(JournalCreate, "", newPath)
+
+	return nil
+}
+
+// LinkFile 为文件创建硬链接，跨设备无法创建硬链接时改为复制，并返回错误信息
+//
+// oldPath 字符串参数，传入文件原始路径，
+// newPath 字符串参数，传入硬链接路径。
+func LinkFile(oldPath, newPath string) error {
+	// 创建目录
+	err := MkdirAll(filepath.Dir(newPath))
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 创建硬链接
+	err = os.Link(oldPath, newPath)
+	// 跨设备
+	if isCrossDevice(err) {
+		return CopyFile(oldPath, newPath)
+	}
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 记录到整理日志
+	journalRecord(JournalCreate, "", newPath)
+
+	return nil
+}
+
+// SymlinkFile 创建指向原文件的符号链接，并返回错误信息
+//
+// oldPath 字符串参数，传入文件原始路径，
+// newPath 字符串参数，传入符号链接路径。
+func SymlinkFile(oldPath, newPath string) error {
+	// 获取绝对路径，避免链接随目录变化失效
+	target, err := filepath.Abs(oldPath)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 创建目录
+	err = MkdirAll(filepath.Dir(newPath))
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 创建符号链接
+	err = os.Symlink(target, newPath)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 记录到整理日志
+	journalRecord(JournalCreate, "", newPath)
+
+	return nil
+}
+
+// 跨设备移动，复制并校验后删除原文件
+func moveCrossDevice(oldPath, newPath string) error {
+	// 复制并校验
+	err := copyVerify(oldPath, newPath)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+
+	// 删除原文件
+	err = os.Remove(oldPath)
+	// 删除失败则删除副本，保证文件只存在一份
+	if err != nil {
+		_ = os.Remove(newPath)
+	}
+
+	return err
+}
+
+// 检查是否为跨设备错误
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// 复制文件，完成后重新读取目标文件并校验md5，校验失败则删除目标文件
+func copyVerify(oldPath, newPath string) error {
+	// 打开原文件
+	src, err := os.Open(oldPath)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// 获取文件信息
+	info, err := src.Stat()
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	// 目标文件已存在
+	if Exists(newPath) {
+		return fmt.Errorf("%s: 文件已存在", newPath)
+	}
+
+	// 先复制到临时文件，避免留下不完整的文件
+	tmp := newPath + ".part"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	// 检查错误
+	if err != nil {
+		return err
+	}
+
+	// 复制同时计算md5
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(dst, hash), newProgressReader(src, newPath, info.Size()))
+	// 同步到磁盘
+	if err == nil {
+		err = dst.Sync()
+	}
+	// 关闭
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	// 检查错误
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// 校验
+	if err = verifyFile(tmp, info.Size(), hash.Sum(nil)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s: 复制校验失败, %s", oldPath, err)
+	}
+
+	// 保留修改时间
+	_ = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+
+	// 重命名为目标文件
+	if err = os.Rename(tmp, newPath); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// 校验文件大小及md5
+func verifyFile(file string, size int64, sum []byte) error {
+	// 检查大小
+	if local := GetFileSize(file); local != size {
+		return fmt.Errorf("文件大小不一致: %d != %d", local, size)
+	}
+
+	// 打开文件
+	f, err := os.Open(file)
+	// 检查错误
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 计算md5
+	hash := md5.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return err
+	}
+	// 比较
+	if !bytes.Equal(hash.Sum(nil), sum) {
+		return fmt.Errorf("md5 不一致")
+	}
+
+	return nil
+}
+
+// 复制进度读取对象
+type progressReader struct {
+	r     io.Reader
+	fn    ProgressFunc
+	file  string
+	done  int64
+	total int64
+	last  time.Time
+}
+
+// 创建复制进度读取对象，没有设置进度回调时直接返回原读取对象
+func newProgressReader(r io.Reader, file string, total int64) io.Reader {
+	progress.Lock()
+	fn := progress.fn
+	progress.Unlock()
+
+	// 是否需要回调
+	if fn == nil {
+		return r
+	}
+
+	return &progressReader{r: r, fn: fn, file: file, total: total, last: time.Now()}
+}
+
+// Read 读取数据并按间隔回调进度
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+
+	// 完成或到达回调间隔
+	if err == io.EOF || time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.fn(p.file, p.done, p.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,249 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// RecordModeRecord 录制模式，正常访问网络并保存所有响应
+	RecordModeRecord = "record"
+	// RecordModeReplay 回放模式，不访问网络，仅从录制文件中返回响应
+	RecordModeReplay = "replay"
+
+	// 录制索引文件名称
+	recordIndex = "index.json"
+)
+
+// 录制条目
+type recordEntry struct {
+	Method string `json:"method"`         // 请求类型
+	URL    string `json:"url"`            // 请求地址
+	Status int    `json:"status"`         // 响应状态码
+	Type   string `json:"type,omitempty"` // 响应内容类型
+	File   string `json:"file"`           // 响应内容文件
+	// 是否为按页面结构手工构造的响应，录制模式下重新录制后将被真实响应替换
+	Synthetic bool `json:"synthetic,omitempty"`
+}
+
+// Recorder 请求录制及回放对象，
+// 录制文件由索引文件 index.json 及各响应内容文件组成，均存放于同一目录中。
+type Recorder struct {
+	mu      sync.Mutex
+	mode    string        // 录制模式
+	dir     string        // 录制文件目录
+	entries []recordEntry // 录制条目
+}
+
+// NewRecorder 返回一个被初始化的录制对象，
+// 录制文件目录中已有的索引将被读取，录制模式下相同请求的条目将被覆盖。
+//
+// mode 字符串参数，传入录制模式，参见常量定义，
+// dir 字符串参数，传入录制文件目录。
+func NewRecorder(mode, dir string) (*Recorder, error) {
+	// 检查模式
+	if mode != RecordModeRecord && mode != RecordModeReplay {
+		return nil, fmt.Errorf("不支持的录制模式: %s", mode)
+	}
+
+	// 实例化
+	r := &Recorder{mode: mode, dir: dir}
+
+	// 读取索引
+	data, err := ioutil.ReadFile(filepath.Join(dir, recordIndex))
+	// 检查
+	if err != nil {
+		// 录制模式下允许索引不存在
+		if os.IsNotExist(err) && mode == RecordModeRecord {
+			return r, nil
+		}
+
+		return nil, err
+	}
+
+	// 解析索引
+	if err = json.Unmarshal(data, &r.entries); err != nil {
+		return nil, fmt.Errorf("%s [Json]: %s", filepath.Join(dir, recordIndex), err)
+	}
+
+	return r, nil
+}
+
+// Wrap 将录制对象包装为请求传输对象，
+// 录制模式下使用 base 发起真实请求，回放模式下不使用 base。
+//
+// base 请求传输对象，传入真实网络请求所使用的传输对象。
+func (r *Recorder) Wrap(base http.RoundTripper) http.RoundTripper {
+	return &recordTransport{recorder: r, base: base}
+}
+
+// 录制传输对象
+type recordTransport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+// RoundTrip 执行请求
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 回放
+	if t.recorder.mode == RecordModeReplay {
+		return t.recorder.replay(req)
+	}
+
+	// 执行真实请求
+	res, err := t.base.RoundTrip(req)
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取内容
+	data, err := ioutil.ReadAll(res.Body)
+	// 关闭
+	_ = res.Body.Close()
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	// 保存响应
+	if err = t.recorder.save(req, res, data); err != nil {
+		return nil, err
+	}
+
+	// 重新设置内容
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	return res, nil
+}
+
+// 从录制文件中回放响应
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 查找条目
+	entry, ok := r.find(req.Method, req.URL.String())
+	// 未找到
+	if !ok {
+		return nil, fmt.Errorf("%s %s [Replay]: 没有录制的响应", req.Method, req.URL)
+	}
+
+	// 读取内容
+	data, err := ioutil.ReadFile(filepath.Join(r.dir, entry.File))
+	// 检查
+	if err != nil {
+		return nil, err
+	}
+
+	// 组合响应
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+	// 设置内容类型
+	if entry.Type != "" {
+		res.Header.Set("Content-Type", entry.Type)
+	}
+
+	return res, nil
+}
+
+// 保存响应到录制文件
+func (r *Recorder) save(req *http.Request, res *http.Response, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 创建目录
+	if err := os.MkdirAll(r.dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// 组合条目
+	entry := recordEntry{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: res.StatusCode,
+		Type:   res.Header.Get("Content-Type"),
+	}
+
+	// 是否已录制过
+	if old, ok := r.find(entry.Method, entry.URL); ok {
+		// 覆盖原有条目
+		entry.File = old.File
+		for i := range r.entries {
+			if r.entries[i].File == old.File {
+				r.entries[i] = entry
+			}
+		}
+	} else {
+		// 新增条目
+		entry.File = fmt.Sprintf("%03d%s", len(r.entries)+1, recordExt(entry.Type))
+		r.entries = append(r.entries, entry)
+	}
+
+	// 写入内容
+	if err := ioutil.WriteFile(filepath.Join(r.dir, entry.File), data, 0644); err != nil {
+		return err
+	}
+
+	// 写入索引
+	index, err := json.MarshalIndent(r.entries, "", "  ")
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(r.dir, recordIndex), index, 0644)
+}
+
+// 查找录制条目
+func (r *Recorder) find(method, uri string) (recordEntry, bool) {
+	for _, entry := range r.entries {
+		if strings.EqualFold(entry.Method, method) && entry.URL == uri {
+			return entry, true
+		}
+	}
+
+	return recordEntry{}, false
+}
+
+// 根据内容类型获取录制文件扩展名
+func recordExt(contentType string) string {
+	// 解析类型
+	t, _, err := mime.ParseMediaType(contentType)
+	// 检查
+	if err != nil {
+		return ".bin"
+	}
+
+	switch {
+	case t == "text/html":
+		return ".html"
+	case strings.HasSuffix(t, "json"):
+		return ".json"
+	case t == "image/jpeg":
+		return ".jpg"
+	case t == "image/png":
+		return ".png"
+	case strings.HasPrefix(t, "text/"):
+		return ".txt"
+	}
+
+	return ".bin"
+}
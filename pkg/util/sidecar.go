@@ -0,0 +1,78 @@
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// 字幕等附属文件扩展列表
+var sidecarExts = map[string]string{
+	".srt": ".srt",
+	".ass": ".ass",
+	".ssa": ".ssa",
+	".vtt": ".vtt",
+	".sub": ".sub",
+	".idx": ".idx",
+}
+
+// Sidecar 视频附属文件，如与视频同名的字幕文件
+type Sidecar struct {
+	File   string // 文件路径
+	Suffix string // 视频名称之后的部分，如 .srt、.zh.srt
+	Lang   string // 语言标识，如 zh，没有则为空
+}
+
+// FindSidecars 查找与视频位于同一目录且同名的附属文件，
+// 名称比较不区分大小写，支持带语言标识的文件，如 ABP-123.zh.srt。
+//
+// video 字符串参数，传入视频文件路径。
+func FindSidecars(video string) []Sidecar {
+	// 视频目录及名称
+	dir := filepath.Dir(video)
+	base := strings.TrimSuffix(filepath.Base(video), filepath.Ext(video))
+
+	// 读取目录
+	files, err := ioutil.ReadDir(dir)
+	// 检查
+	if err != nil {
+		return nil
+	}
+
+	// 附属文件列表
+	var sidecars []Sidecar
+	for _, f := range files {
+		// 忽略目录
+		if f.IsDir() {
+			continue
+		}
+
+		// 检查扩展名
+		name := f.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if _, ok := sidecarExts[ext]; !ok {
+			continue
+		}
+
+		// 检查名称
+		if len(name) <= len(base) || !strings.EqualFold(name[:len(base)], base) || name[len(base)] != '.' {
+			continue
+		}
+
+		// 名称之后的部分
+		suffix := name[len(base):]
+		// 语言标识，取第一段
+		var lang string
+		if middle := strings.Trim(strings.TrimSuffix(suffix, filepath.Ext(name)), "."); middle != "" {
+			lang = strings.Split(middle, ".")[0]
+		}
+
+		sidecars = append(sidecars, Sidecar{
+			File:   filepath.Join(dir, name),
+			Suffix: suffix[:len(suffix)-len(ext)] + ext,
+			Lang:   lang,
+		})
+	}
+
+	return sidecars
+}
@@ -5,13 +5,68 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
+const (
+	// DefaultFilename 默认文件名称规则
+	DefaultFilename = "{number}{variant}{part}"
+	// 文件名称最大字节数，为扩展名、字幕语言及 .vsmeta 后缀预留长度
+	maxFileNameBytes = 200
+)
+
+// 版本标识正则，如 -C 中文字幕、-U 无码破解、-UC 无码破解中文字幕、-4K
+var variantRegexp = regexp.MustCompile(`[-_ .](c|ch|uc|u|4k|2160p)$`)
+
+// CodeInfo 番号提取结果，包含番号及文件名中的版本标识
+type CodeInfo struct {
+	Code       string // 番号
+	Sub        bool   // 是否中文字幕
+	Uncensored bool   // 是否无码破解
+	UHD        bool   // 是否4K
+}
+
+// Variant 获取版本标识，用于文件命名，如 -C、-U、-UC、-4K、-UC-4K，没有则返回空
+func (c CodeInfo) Variant() string {
+	// 版本标识
+	var variant string
+
+	// 无码破解
+	if c.Uncensored {
+		variant += "-U"
+		// 无码破解中文字幕
+		if c.Sub {
+			variant += "C"
+		}
+	} else if c.Sub {
+		variant += "-C"
+	}
+	// 4K
+	if c.UHD {
+		variant += "-4K"
+	}
+
+	return variant
+}
+
 // GetCode 从文件中提取番号信息
 //
 // filename 字符串，传入要提取的文件名称，
 // filter 字符串，要对文件名称进行过滤的规则信息。
 func GetCode(filename string, regs, filters []string) string {
+	return ParseCode(filename, regs, filters).Code
+}
+
+// ParseCode 从文件中提取番号及版本标识信息，
+// 版本标识将从文件名末尾去除，不影响番号提取。
+//
+// filename 字符串，传入要提取的文件名称，
+// regs 字符串数组，传入优先匹配番号的正则，
+// filters 字符串数组，传入要对文件名称进行过滤的规则信息。
+func ParseCode(filename string, regs, filters []string) CodeInfo {
+	// 提取结果
+	var info CodeInfo
+
 	// 获取正确文件名
 	filename = filepath.Base(strings.ToLower(filename))
 	// 删除扩展名
@@ -19,11 +74,15 @@ func GetCode(filename string, regs, filters []string) string {
 	// 转为小写
 	filename = strings.ToLower(filename)
 
+	// 去除版本标识
+	filename = stripVariant(filename, &info)
+
 	// 优先提取
 	for _, reg := range regs {
 		val := regexp.MustCompile(reg).FindString(filename)
 		if len(val) > 0 {
-			return val
+			info.Code = val
+			return info
 		}
 	}
 
@@ -36,40 +95,128 @@ func GetCode(filename string, regs, filters []string) string {
 	filename = strings.ReplaceAll(filename, ".", "-")
 	// 过滤空格
 	filename = strings.TrimSpace(filename)
+	// 过滤后再次去除版本标识，如 -c-hd
+	filename = stripVariant(filename, &info)
 
-	return filename
+	info.Code = filename
+
+	return info
 }
 
-// GetNumberPath 通过配置信息，获取到正确的保存路径
+// 去除文件名末尾的版本标识并记录，可能同时存在多个
+func stripVariant(filename string, info *CodeInfo) string {
+	for {
+		m := variantRegexp.FindStringSubmatch(filename)
+		if len(m) == 0 {
+			return filename
+		}
+
+		switch m[1] {
+		case "c", "ch":
+			info.Sub = true
+		case "u":
+			info.Uncensored = true
+		case "uc":
+			info.Uncensored = true
+			info.Sub = true
+		case "4k", "2160p":
+			info.UHD = true
+		}
+		filename = strings.TrimSuffix(filename, m[0])
+	}
+}
+
+// GetNumberPath 通过配置信息，获取到正确的保存路径，
+// 保存规则使用路径模板语法，参见 RenderTemplate。
 //
 // replaceStr map对象，通过转换后的媒体各项数据，
 // cfg 配置信息，用以读取保存路径规则。
 func GetNumberPath(replaceStr map[string]string, cfg *ConfigStruct) string {
-	// 获取运行路径
-	base := GetRunPath()
+	// 获取输出路径
+	base := OutputPath(cfg)
 	// 组合路径
 	base = base + "/" + cfg.Path.Success
-	// 获取保存规则
-	rule := cfg.Path.Directory
-	// 循环替换
-	for key, val := range replaceStr {
-		// 检查内容长度
-		if len(val) > 50 {
-			val = "ManyMany..."
+	// 渲染保存规则，语法已在整理前通过 CheckTemplate 检查
+	rule, _ := RenderTemplate(cfg.Path.Directory, replaceStr, sanitizeValue)
+
+	// 过滤特殊字符
+	rule = filterPath(rule)
+
+	// 逐级截断过长的目录名称，并去除空目录
+	var dirs []string
+	for _, dir := range strings.Split(rule, "/") {
+		dir = strings.Trim(TruncateBytes(dir, maxFileNameBytes), " ")
+		if dir != "" {
+			dirs = append(dirs, dir)
 		}
-		rule = strings.ReplaceAll(rule, key, val)
 	}
 
+	return base + "/" + strings.Join(dirs, "/")
+}
+
+// GetFileName 通过配置信息，获取整理后的文件名称，不含扩展名，
+// 内容中的斜线及特殊字符将被过滤，并按字节截断以符合文件系统的名称长度限制。
+//
+// replaceStr map对象，通过转换后的媒体各项数据，
+// cfg 配置信息，用以读取文件名称规则。
+func GetFileName(replaceStr map[string]string, cfg *ConfigStruct) string {
+	// 获取文件名称规则
+	rule := cfg.Path.Filename
+	// 默认规则
+	if strings.TrimSpace(rule) == "" {
+		rule = DefaultFilename
+	}
+	// 渲染文件名称规则，语法已在整理前通过 CheckTemplate 检查
+	name, _ := RenderTemplate(rule, replaceStr, sanitizeValue)
+
+	// 过滤特殊字符及斜线
+	name = strings.ReplaceAll(filterPath(name), "/", "")
+	// 截断
+	name = TruncateBytes(name, maxFileNameBytes)
+	// 去除首尾空格及点号
+	name = strings.Trim(name, " .")
+
+	// 名称为空时使用番号
+	if name == "" {
+		name = sanitizeValue(replaceStr["{number}"])
+	}
+
+	return name
+}
+
+// TruncateBytes 按字节截断字符串，不会截断多字节字符
+//
+// s 字符串参数，传入要截断的字符串，
+// n 整数参数，传入最大字节数。
+func TruncateBytes(s string, n int) string {
+	// 无需截断
+	if len(s) <= n {
+		return s
+	}
+
+	// 向前查找完整字符的边界
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// 过滤路径中的特殊字符
+func filterPath(rule string) string {
 	// 定义特殊字符数组
 	filter := []string{"\\", ":", "*", "?", `"`, "<", ">", "|"}
 	// 循环过滤
 	for _, v := range filter {
 		rule = strings.ReplaceAll(rule, v, "")
 	}
-	// 多余的反斜线
-	rule = strings.ReplaceAll(rule, "//", "/")
 
-	return base + "/" + rule
+	return rule
+}
+
+// 过滤替换内容中的路径分隔符，避免标题或演员中的斜线产生多余的目录
+func sanitizeValue(val string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(val)
 }
 
 // CheckDomainPrefix 检查域名最后是否存在斜线并返回无斜线域名
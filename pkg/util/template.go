@@ -0,0 +1,309 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 路径模板语法：
+//
+//   {name}              替换为变量内容，未知变量将原样保留，整理前的检查会将其视为错误
+//   {name|默认值}       变量为空时使用默认值
+//   {name:40}           截取前 40 个字符，列表变量（如 actors）则取前 40 项
+//   {name:upper}        转换为大写，可用 lower 转换为小写
+//   {name:20:upper|无}  修饰符可以组合使用
+//   {if name}...{else}...{end}  变量不为空时输出第一部分，否则输出 else 部分，
+//                               条件前加 ! 表示取反，条件可以嵌套
+
+// CheckTemplate 检查保存路径及文件名称模板的语法、变量及修饰符，
+// 整理前调用，避免语法错误或变量名称拼写错误时生成错误的目录结构。
+//
+// cfg 配置信息，用以读取保存路径及文件名称规则，
+// data map对象，传入可用的变量，键名格式为 {name}，通常为 Media.ConvertMap 的结果。
+func CheckTemplate(cfg *ConfigStruct, data map[string]string) error {
+	rules := []struct {
+		name string
+		rule string
+	}{
+		{"path.directory", cfg.Path.Directory},
+		{"path.filename", cfg.Path.Filename},
+	}
+	for _, r := range rules {
+		if err := ValidateTemplate(r.rule, data); err != nil {
+			return fmt.Errorf("%s: %s, 可使用 AVMeta template test 检查", r.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateTemplate 检查单个模板的语法，以及其中的变量、条件及修饰符名称是否可用。
+//
+// rule 字符串参数，传入模板内容，
+// data map对象，传入可用的变量，键名格式为 {name}。
+func ValidateTemplate(rule string, data map[string]string) error {
+	// 检查语法
+	_, err := RenderTemplate(rule, data, nil)
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 检查名称
+	var errs []string
+	for _, token := range templateTokens(rule) {
+		switch {
+		case strings.HasPrefix(token, "if "):
+			// 条件变量
+			name := strings.TrimPrefix(strings.TrimSpace(token[3:]), "!")
+			if _, ok := data["{"+name+"}"]; !ok {
+				errs = append(errs, fmt.Sprintf("{%s} 中的变量 %q 不存在", token, name))
+			}
+		case token == "else", token == "end":
+		default:
+			// 去除默认值
+			expr := token
+			if i := strings.IndexByte(expr, '|'); i >= 0 {
+				expr = expr[:i]
+			}
+			parts := strings.Split(expr, ":")
+			// 变量
+			name := strings.TrimSpace(parts[0])
+			if _, ok := data["{"+name+"}"]; !ok {
+				errs = append(errs, fmt.Sprintf("{%s} 中的变量 %q 不存在", token, name))
+			}
+			// 修饰符
+			for _, mod := range parts[1:] {
+				if !templateModifier(strings.TrimSpace(mod)) {
+					errs = append(errs, fmt.Sprintf("{%s} 中的修饰符 %q 不存在, 可用: upper, lower, 数字", token, mod))
+				}
+			}
+		}
+	}
+
+	// 是否有错误
+	if len(errs) > 0 {
+		return fmt.Errorf("模板变量错误: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// 获取模板中所有 {} 内的内容，语法已检查
+func templateTokens(rule string) []string {
+	var tokens []string
+	for {
+		// 查找变量开始
+		start := strings.IndexByte(rule, '{')
+		if start < 0 {
+			return tokens
+		}
+		// 查找变量结束
+		end := strings.IndexByte(rule[start:], '}')
+		if end < 0 {
+			return tokens
+		}
+		end += start
+		tokens = append(tokens, rule[start+1:end])
+		rule = rule[end+1:]
+	}
+}
+
+// 检查修饰符是否可用
+func templateModifier(mod string) bool {
+	// 大小写
+	if strings.EqualFold(mod, "upper") || strings.EqualFold(mod, "lower") {
+		return true
+	}
+	// 数字
+	n, err := strconv.Atoi(mod)
+
+	return err == nil && n >= 0
+}
+
+// RenderTemplate 使用给定数据渲染路径模板，
+// 模板语法错误时依然返回尽可能渲染的结果，以及错误信息。
+//
+// rule 字符串参数，传入模板内容，
+// data map对象，传入替换数据，键名格式为 {name}，列表以 "," 分隔，
+// escape 函数参数，传入变量内容的过滤函数，为 nil 则不过滤。
+func RenderTemplate(rule string, data map[string]string, escape func(string) string) (string, error) {
+	// 输出内容
+	var out strings.Builder
+	// 条件栈，记录每层条件是否输出
+	var stack []templateCond
+	// 语法错误
+	var errs []string
+
+	// 当前是否输出
+	active := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].active
+	}
+
+	for len(rule) > 0 {
+		// 查找变量开始
+		start := strings.IndexByte(rule, '{')
+		if start < 0 {
+			if active() {
+				out.WriteString(rule)
+			}
+			break
+		}
+		// 查找变量结束
+		end := strings.IndexByte(rule[start:], '}')
+		if end < 0 {
+			if active() {
+				out.WriteString(rule)
+			}
+			errs = append(errs, fmt.Sprintf("%q 缺少 }", rule[start:]))
+			break
+		}
+		end += start
+
+		// 输出之前的内容
+		if active() {
+			out.WriteString(rule[:start])
+		}
+
+		// 变量内容
+		token := rule[start+1 : end]
+		rule = rule[end+1:]
+
+		switch {
+		case strings.HasPrefix(token, "if "):
+			// 条件开始
+			name := strings.TrimSpace(token[3:])
+			negate := strings.HasPrefix(name, "!")
+			name = strings.TrimPrefix(name, "!")
+
+			// 条件是否成立
+			ok := templateTruthy(data["{"+name+"}"])
+			if negate {
+				ok = !ok
+			}
+			// 上级不输出则本级也不输出
+			parent := active()
+			stack = append(stack, templateCond{active: parent && ok, parent: parent, matched: ok})
+		case token == "else":
+			// 条件分支
+			if len(stack) == 0 {
+				errs = append(errs, "{else} 缺少对应的 {if}")
+				continue
+			}
+			top := &stack[len(stack)-1]
+			top.active = top.parent && !top.matched
+		case token == "end":
+			// 条件结束
+			if len(stack) == 0 {
+				errs = append(errs, "{end} 缺少对应的 {if}")
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			// 变量
+			if !active() {
+				continue
+			}
+			val, ok := renderTemplateVar(token, data, escape)
+			// 未知变量原样保留
+			if !ok {
+				out.WriteString("{" + token + "}")
+				continue
+			}
+			out.WriteString(val)
+		}
+	}
+
+	// 未结束的条件
+	if len(stack) > 0 {
+		errs = append(errs, fmt.Sprintf("缺少 %d 个 {end}", len(stack)))
+	}
+
+	// 是否有错误
+	if len(errs) > 0 {
+		return out.String(), fmt.Errorf("模板语法错误: %s", strings.Join(errs, "; "))
+	}
+
+	return out.String(), nil
+}
+
+// 条件状态
+type templateCond struct {
+	active  bool // 当前分支是否输出
+	parent  bool // 上级是否输出
+	matched bool // 条件是否成立
+}
+
+// 渲染单个变量，返回内容及变量是否存在
+func renderTemplateVar(token string, data map[string]string, escape func(string) string) (string, bool) {
+	// 默认值
+	var def string
+	var hasDef bool
+	if i := strings.IndexByte(token, '|'); i >= 0 {
+		def, hasDef = token[i+1:], true
+		token = token[:i]
+	}
+
+	// 修饰符
+	parts := strings.Split(token, ":")
+	name := strings.TrimSpace(parts[0])
+
+	// 获取变量
+	val, ok := data["{"+name+"}"]
+	if !ok {
+		return "", false
+	}
+
+	// 过滤内容
+	if escape != nil {
+		val = escape(val)
+	}
+
+	// 应用修饰符
+	for _, mod := range parts[1:] {
+		mod = strings.TrimSpace(mod)
+		switch {
+		case strings.EqualFold(mod, "upper"):
+			val = strings.ToUpper(val)
+		case strings.EqualFold(mod, "lower"):
+			val = strings.ToLower(val)
+		default:
+			// 数字修饰符
+			n, err := strconv.Atoi(mod)
+			if err != nil || n < 0 {
+				continue
+			}
+			// 列表取前 n 项
+			if name == "actors" {
+				items := strings.Split(val, ",")
+				if len(items) > n {
+					items = items[:n]
+				}
+				val = strings.Join(items, ",")
+				continue
+			}
+			// 截取前 n 个字符
+			if r := []rune(val); len(r) > n {
+				val = strings.TrimSpace(string(r[:n]))
+			}
+		}
+	}
+
+	// 使用默认值
+	if val == "" && hasDef {
+		val = def
+	}
+
+	return val, true
+}
+
+// 检查条件变量是否成立
+func templateTruthy(val string) bool {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "", "0", "false":
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,32 @@
+package util
+
+import "testing"
+
+func TestValidateTemplate(t *testing.T) {
+	// 可用变量
+	data := map[string]string{
+		"{number}": "", "{title}": "", "{actors}": "", "{series}": "", "{uncensored}": "", "{part}": "",
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{name: "variables", rule: "{series|未知系列}/{actors:3}/{number:upper}{part}"},
+		{name: "condition", rule: "{if !uncensored}有码{else}无码{end}/{title:40:lower|无}"},
+		{name: "unknown variable", rule: "{number} {titel}", wantErr: true},
+		{name: "unknown condition", rule: "{if censored}有码{end}", wantErr: true},
+		{name: "unknown modifier", rule: "{number:uppr}", wantErr: true},
+		{name: "negative length", rule: "{title:-1}", wantErr: true},
+		{name: "syntax", rule: "{if uncensored}无码", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTemplate(tt.rule, data); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplate(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package util
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher 目录监控，发现新增文件后等待其大小稳定（下载完成）再交由回调处理，
+// 优先使用文件系统通知，不可用时使用轮询方式。
+type Watcher struct {
+	dir    string                 // 监控目录
+	filter func(file string) bool // 文件过滤，返回 true 的文件才会被处理
+	notify *fsnotify.Watcher      // 文件系统通知，为 nil 时使用轮询
+
+	pending map[string]*watchFile // 等待大小稳定的文件
+	done    map[string]watchStat  // 已处理的文件
+}
+
+// 等待中的文件
+type watchFile struct {
+	stat  watchStat // 最后一次检查的文件信息
+	since time.Time // 文件信息保持不变的开始时间
+}
+
+// 文件信息
+type watchStat struct {
+	size int64 // 文件大小
+	mod  int64 // 修改时间，纳秒
+}
+
+// NewWatcher 返回一个目录监控对象。
+//
+// dir 字符串参数，传入要监控的目录，
+// filter 函数参数，传入文件过滤函数，返回 true 的文件才会被处理。
+func NewWatcher(dir string, filter func(file string) bool) *Watcher {
+	return &Watcher{
+		dir:     dir,
+		filter:  filter,
+		pending: make(map[string]*watchFile),
+		done:    make(map[string]watchStat),
+	}
+}
+
+// Notify 启用文件系统通知，并返回错误信息，
+// 返回错误时监控将使用轮询方式。
+func (w *Watcher) Notify() error {
+	// 创建通知
+	notify, err := fsnotify.NewWatcher()
+	// 检查
+	if err != nil {
+		return err
+	}
+
+	// 监控所有子目录
+	err = watchDirs(notify, w.dir)
+	// 检查
+	if err != nil {
+		_ = notify.Close()
+		return err
+	}
+
+	w.notify = notify
+
+	return nil
+}
+
+// Run 开始监控目录，直到上下文被取消，
+// 启动时目录中已存在的文件同样会被处理。
+//
+// ctx 上下文参数，传入监控所使用的上下文，
+// interval 时间参数，传入检查间隔，轮询方式下同时为扫描间隔，
+// stable 时间参数，传入文件大小保持不变多久后视为下载完成，
+// ready 函数参数，传入文件下载完成后的处理函数。
+func (w *Watcher) Run(ctx context.Context, interval, stable time.Duration, ready func(files []string)) error {
+	// 关闭通知
+	if w.notify != nil {
+		defer w.notify.Close()
+	}
+
+	// 扫描已存在的文件
+	w.scan(w.dir)
+
+	// 定时检查
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 通知事件，轮询方式下为 nil，不会被选中
+	var events chan fsnotify.Event
+	var errs chan error
+	if w.notify != nil {
+		events = w.notify.Events
+		errs = w.notify.Errors
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			// 通知已关闭
+			if !ok {
+				return nil
+			}
+			w.event(ev)
+		case err, ok := <-errs:
+			// 通知已关闭
+			if !ok {
+				return nil
+			}
+			// 通知队列溢出等错误，重新扫描以免遗漏文件
+			if err != nil {
+				w.scan(w.dir)
+			}
+		case <-ticker.C:
+			// 轮询扫描
+			if w.notify == nil {
+				w.scan(w.dir)
+			}
+			// 检查下载完成的文件
+			if files := w.check(stable); len(files) > 0 {
+				ready(files)
+			}
+		}
+	}
+}
+
+// 处理通知事件
+func (w *Watcher) event(ev fsnotify.Event) {
+	// 只处理新建、写入及移入
+	if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+		return
+	}
+
+	// 获取文件信息
+	info, err := os.Stat(ev.Name)
+	// 检查，重命名事件时原文件已不存在
+	if err != nil {
+		return
+	}
+
+	// 新建目录，监控并扫描其中的文件
+	if info.IsDir() {
+		_ = watchDirs(w.notify, ev.Name)
+		w.scan(ev.Name)
+		return
+	}
+
+	w.add(ev.Name)
+}
+
+// 扫描目录中的文件
+func (w *Watcher) scan(dir string) {
+	// 当前存在的文件
+	exists := make(map[string]bool)
+
+	_ = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		// 错误
+		if info == nil || info.IsDir() {
+			return nil
+		}
+		exists[file] = true
+		w.add(file)
+
+		return nil
+	})
+
+	// 清理已不存在的处理记录
+	if dir == w.dir {
+		for file := range w.done {
+			if !exists[file] {
+				delete(w.done, file)
+			}
+		}
+	}
+}
+
+// 加入等待列表
+func (w *Watcher) add(file string) {
+	// 已在等待中
+	if _, ok := w.pending[file]; ok {
+		return
+	}
+
+	// 过滤文件
+	if w.filter != nil && !w.filter(file) {
+		return
+	}
+
+	// 获取文件信息
+	stat, ok := watchStatOf(file)
+	// 检查
+	if !ok {
+		return
+	}
+
+	// 已处理且未发生变化
+	if done, ok := w.done[file]; ok && done == stat {
+		return
+	}
+
+	w.pending[file] = &watchFile{stat: stat, since: time.Now()}
+}
+
+// 检查等待列表，返回大小已稳定的文件
+func (w *Watcher) check(stable time.Duration) []string {
+	// 下载完成的文件
+	var files []string
+	now := time.Now()
+
+	for file, f := range w.pending {
+		// 获取文件信息
+		stat, ok := watchStatOf(file)
+		// 文件已被删除或移走
+		if !ok {
+			delete(w.pending, file)
+			continue
+		}
+
+		// 文件仍在变化
+		if stat != f.stat || stat.size == 0 {
+			f.stat = stat
+			f.since = now
+			continue
+		}
+
+		// 保持不变的时长不足
+		if now.Sub(f.since) < stable {
+			continue
+		}
+
+		delete(w.pending, file)
+		w.done[file] = stat
+		files = append(files, file)
+	}
+
+	// 按路径排序，便于合并分段文件
+	sort.Strings(files)
+
+	return files
+}
+
+// 获取文件大小及修改时间
+func watchStatOf(file string) (watchStat, bool) {
+	// 获取文件信息
+	info, err := os.Stat(file)
+	// 检查
+	if err != nil || info.IsDir() {
+		return watchStat{}, false
+	}
+
+	return watchStat{size: info.Size(), mod: info.ModTime().UnixNano()}, true
+}
+
+// 监控目录及其所有子目录
+func watchDirs(notify *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		// 错误
+		if err != nil {
+			return err
+		}
+		// 只监控目录
+		if !info.IsDir() {
+			return nil
+		}
+
+		return notify.Add(file)
+	})
+}